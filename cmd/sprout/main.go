@@ -17,6 +17,10 @@ func main() {
 		port       = flag.Int("port", 1313, "Port for serve command")
 		rebuild    = flag.String("rebuild", "request", "Rebuild mode: manual, request, watch")
 		livereload = flag.Bool("livereload", false, "Enable livereload (dev only)")
+		collisions = flag.Bool("allow-collisions", false, "Warn instead of failing when two pages share a permalink")
+		drafts     = flag.Bool("drafts", false, "Include pages marked as draft")
+		future     = flag.Bool("future", false, "Include pages with a publish date in the future")
+		expired    = flag.Bool("expired", false, "Include pages past their expiry date")
 	)
 	flag.Parse()
 
@@ -25,10 +29,18 @@ func main() {
 	args := flag.Args()
 	if len(args) == 0 {
 		fmt.Fprintf(os.Stderr, "Usage: sprout <command> [flags]\n")
-		fmt.Fprintf(os.Stderr, "Commands: init, build, serve\n")
+		fmt.Fprintf(os.Stderr, "Commands: init, build, serve, gen\n")
 		os.Exit(1)
 	}
 
+	buildOpts := app.BuildOptions{
+		Clean:           *clean,
+		AllowCollisions: *collisions,
+		Drafts:          *drafts,
+		Future:          *future,
+		Expired:         *expired,
+	}
+
 	command := args[0]
 	switch command {
 	case "init":
@@ -38,21 +50,34 @@ func main() {
 		}
 		fmt.Printf("Initialized Sprout site in %s\n", *root)
 	case "build":
-		result, err := app.Build(*root, *clean)
+		result, err := app.Build(*root, buildOpts)
 		if err != nil {
 			logx.Errorf("%v", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Build complete: %d pages, %d assets\n", result.BuiltPages, result.CopiedAssets)
+		fmt.Printf("Build complete: %d pages, %d skipped, %d assets, %d pruned\n", result.BuiltPages, result.SkippedPages, result.CopiedAssets, result.PrunedOutputs)
 	case "serve":
 		rebuildMode := app.RebuildMode(*rebuild)
-		if err := app.Serve(*root, *port, rebuildMode, *livereload); err != nil {
+		if err := app.Serve(*root, *port, rebuildMode, *livereload, buildOpts); err != nil {
+			logx.Errorf("%v", err)
+			os.Exit(1)
+		}
+	case "gen":
+		if len(args) < 2 || args[1] != "highlight-css" {
+			fmt.Fprintf(os.Stderr, "Usage: sprout gen highlight-css <style> > highlight.css\n")
+			os.Exit(1)
+		}
+		var style string
+		if len(args) > 2 {
+			style = args[2]
+		}
+		if err := app.GenHighlightCSS(os.Stdout, style); err != nil {
 			logx.Errorf("%v", err)
 			os.Exit(1)
 		}
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
-		fmt.Fprintf(os.Stderr, "Commands: init, build, serve\n")
+		fmt.Fprintf(os.Stderr, "Commands: init, build, serve, gen\n")
 		os.Exit(1)
 	}
 }
@@ -3,6 +3,11 @@ module sprout
 go 1.25.5
 
 require (
+	github.com/alecthomas/chroma/v2 v2.27.0
 	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/yuin/goldmark v1.7.13
 )
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require github.com/dlclark/regexp2/v2 v2.2.1 // indirect
@@ -2,28 +2,38 @@ package app
 
 import (
 	"fmt"
-	"html/template"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"sprout/internal/assets"
 	"sprout/internal/cache"
 	"sprout/internal/config"
-	"sprout/internal/content"
+	"sprout/internal/feed"
 	"sprout/internal/fsutil"
 	"sprout/internal/logx"
 	"sprout/internal/model"
 	"sprout/internal/router"
+	"sprout/internal/sitemap"
 	tmpl "sprout/internal/template"
 )
 
 type BuildResult struct {
-	BuiltPages   int
-	CopiedAssets int
+	BuiltPages    int
+	SkippedPages  int
+	CopiedAssets  int
+	PrunedOutputs int
 }
 
-func Build(root string, clean bool) (*BuildResult, error) {
+type BuildOptions struct {
+	Clean           bool
+	AllowCollisions bool
+	Drafts          bool
+	Future          bool
+	Expired         bool
+}
+
+func Build(root string, opts BuildOptions) (*BuildResult, error) {
 	if root == "" {
 		return nil, fmt.Errorf("root directory cannot be empty")
 	}
@@ -47,7 +57,7 @@ func Build(root string, clean bool) (*BuildResult, error) {
 		return nil, fmt.Errorf("failed to resolve config: %w", err)
 	}
 
-	if clean {
+	if opts.Clean {
 		if err := os.RemoveAll(resolved.Paths.Public); err != nil {
 			return nil, fmt.Errorf("failed to clean public directory: %w", err)
 		}
@@ -60,13 +70,24 @@ func Build(root string, clean bool) (*BuildResult, error) {
 		return nil, fmt.Errorf("templates directory does not exist: %s", resolved.Paths.Templates)
 	}
 
-	renderer, err := tmpl.NewRenderer(resolved.Paths.Templates)
+	renderer, err := tmpl.NewRenderer(resolved)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create renderer: %w", err)
 	}
 
-	site := model.Site{
+	feedRenderer, err := feed.NewRenderer(resolved.Paths.Templates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create feed renderer: %w", err)
+	}
+
+	sitemapRenderer, err := sitemap.NewRenderer(resolved.Paths.Templates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sitemap renderer: %w", err)
+	}
+
+	site := &model.Site{
 		BaseURL: resolved.BaseURL,
+		Params:  resolved.Params,
 	}
 
 	result := &BuildResult{}
@@ -100,26 +121,154 @@ func Build(root string, clean bool) (*BuildResult, error) {
 		plan = cache.Diff(oldCache, snapshot)
 	}
 
-	logx.Infof("Templates changed: %v, Pages to rebuild: %d, Assets to copy: %d",
-		plan.TemplatesChanged, len(plan.PagesToRebuild), len(plan.AssetsToCopy))
+	logx.Infof("Templates changed: %v, Pages to rebuild: %d, Pages removed: %d, Assets to copy: %d",
+		plan.TemplatesChanged, len(plan.PagesToRebuild), len(plan.PagesRemoved), len(plan.AssetsToCopy))
+
+	contentFiles := make([]string, 0, len(snapshot.ContentFiles))
+	for path := range snapshot.ContentFiles {
+		contentFiles = append(contentFiles, path)
+	}
 
-	if len(plan.PagesToRebuild) > 0 {
-		for _, relPath := range plan.PagesToRebuild {
-			if relPath == "" {
+	loaded, err := loadPages(resolved, contentFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entries, skipped := filterPages(loaded, opts, now)
+	result.SkippedPages = skipped
+
+	entries, err = resolveCollisions(entries, opts.AllowCollisions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every content page's permalink is known before any content is
+	// rendered, so pages can link to each other by source file.
+	refs := newRefs(loaded, entries, resolved.BrokenRefs, func(entry *pageEntry) string {
+		if reason := unpublished(entry.frontMatter, opts, now); reason != "" {
+			return reason
+		}
+		return "is skipped because its permalink collides with another page's"
+	})
+	renderer.SetRefs(func(from, target string) (string, error) {
+		return refs.resolve(from, "", target)
+	})
+
+	// Content is only rendered again when the page's source file, URL or
+	// shortcode templates, or the URLs of the pages it links to, changed.
+	rebuildAll := plan.TemplatesChanged || plan.ConfigChanged
+	contents := make(map[string]cache.ContentRecord, len(entries))
+	for _, entry := range entries {
+		if record, exists := oldCache.Content[entry.key]; exists && !rebuildAll && refs.unchanged(record.Refs) {
+			entry.shortcodes = record.Shortcodes
+			if record.Signature == contentSignature(entry, snapshot.ContentFiles, snapshot.TemplateFiles) {
+				restoreContent(entry, record)
+				contents[entry.key] = record
 				continue
 			}
-			contentPath := filepath.Join(resolved.Paths.Content, relPath)
-			contentPath = filepath.Clean(contentPath)
-			if !strings.HasPrefix(contentPath, resolved.Paths.Content) {
-				return nil, fmt.Errorf("invalid content path: %s", contentPath)
-			}
-			if err := buildPage(contentPath, resolved, site, renderer); err != nil {
-				return nil, fmt.Errorf("failed to build page %s: %w", contentPath, err)
+		}
+
+		if err := renderContent(entry, resolved, renderer, refs); err != nil {
+			return nil, fmt.Errorf("failed to build page %s: %w", entry.contentPath, err)
+		}
+		contents[entry.key] = contentRecord(entry, contentSignature(entry, snapshot.ContentFiles, snapshot.TemplateFiles))
+		logx.Infof("Rendered: %s", entry.key)
+	}
+
+	site.Pages = linkPages(entries)
+	for _, page := range site.Pages {
+		if page.Kind == model.KindPage {
+			site.RegularPages = append(site.RegularPages, page)
+		}
+	}
+
+	generated, taxonomies := buildTaxonomies(resolved, entries)
+	site.Taxonomies = taxonomies
+
+	// Content pages come first, so they win any collision with a generated
+	// page when collisions are allowed.
+	entries, err = resolveCollisions(append(entries, generated...), opts.AllowCollisions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := paginate(entries, resolved, opts.AllowCollisions); err != nil {
+		return nil, err
+	}
+
+	rebuild := make(map[string]bool, len(plan.PagesToRebuild))
+	for _, path := range plan.PagesToRebuild {
+		rebuild[path] = true
+	}
+
+	signature := siteSignature(entries, snapshot.ContentFiles, snapshot.TemplateFiles)
+
+	pages := make(map[string]cache.PageRecord, len(entries))
+	for _, entry := range entries {
+		// Generated pages have no source file in the plan, so template and
+		// config changes have to be checked for them explicitly.
+		if record, exists := oldCache.Pages[entry.key]; exists && !rebuildAll && !rebuild[entry.key] && record.Signature == signature {
+			pages[entry.key] = record
+			continue
+		}
+
+		outputs, err := buildPage(entry, resolved, site, renderer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build page %s: %w", entry.key, err)
+		}
+		pages[entry.key] = cache.PageRecord{
+			Outputs:   outputs,
+			Signature: signature,
+		}
+		result.BuiltPages++
+	}
+
+	for _, entry := range entries {
+		if !hasFeeds(entry.page, resolved.Feeds) {
+			continue
+		}
+		key := "feed:" + entry.key
+		if record, exists := oldCache.Pages[key]; exists && !rebuildAll && !rebuild[entry.key] && record.Signature == signature {
+			pages[key] = record
+			continue
+		}
+
+		outputs, err := buildFeeds(entry, resolved, site, feedRenderer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build feeds for %s: %w", entry.key, err)
+		}
+		pages[key] = cache.PageRecord{
+			Outputs:   outputs,
+			Signature: signature,
+		}
+	}
+
+	// The sitemap is recorded now so that pruning sees it, but written after
+	// the static files are copied: removing a static robots.txt deletes it
+	// from public/, and the generated one has to replace it.
+	files := sitemapFiles(snapshot.StaticFiles)
+	var pendingSitemap *sitemap.Sitemap
+	if len(files) > 0 {
+		data := sitemapData(entries, resolved, site, snapshot.ContentFiles)
+		signature := sitemapSignature(data, files)
+		if record, exists := oldCache.Pages[sitemapKey]; exists && !rebuildAll && record.Signature == signature {
+			pages[sitemapKey] = record
+		} else {
+			pendingSitemap = data
+			pages[sitemapKey] = cache.PageRecord{
+				Outputs:   files,
+				Signature: signature,
 			}
-			result.BuiltPages++
 		}
 	}
 
+	pruned, err := pruneOutputs(resolved.Paths.Public, oldCache.Pages, pages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune stale outputs: %w", err)
+	}
+	result.PrunedOutputs = pruned
+
 	if len(plan.AssetsToCopy) > 0 {
 		if err := assets.CopyChanged(resolved.Paths.Static, resolved.Paths.Public, plan.AssetsToCopy); err != nil {
 			return nil, fmt.Errorf("failed to copy assets: %w", err)
@@ -127,74 +276,72 @@ func Build(root string, clean bool) (*BuildResult, error) {
 		result.CopiedAssets = len(plan.AssetsToCopy)
 	}
 
+	if pendingSitemap != nil {
+		if err := buildSitemap(pendingSitemap, files, resolved, sitemapRenderer); err != nil {
+			return nil, fmt.Errorf("failed to build sitemap: %w", err)
+		}
+	}
+
 	newCache := &cache.Cache{
 		ContentFiles:  snapshot.ContentFiles,
 		TemplateFiles: snapshot.TemplateFiles,
 		StaticFiles:   snapshot.StaticFiles,
 		ConfigFile:    snapshot.ConfigFile,
+		Pages:         pages,
+		Content:       contents,
 	}
 	if err := cache.SaveCache(cachePath, newCache); err != nil {
 		return nil, fmt.Errorf("failed to save cache: %w", err)
 	}
 
-	logx.Infof("Built %d pages, copied %d assets", result.BuiltPages, result.CopiedAssets)
+	logx.Infof("Built %d pages, skipped %d, copied %d assets, pruned %d outputs", result.BuiltPages, result.SkippedPages, result.CopiedAssets, result.PrunedOutputs)
 
 	return result, nil
 }
 
-func buildPage(contentPath string, resolved *model.ResolvedConfig, site model.Site, renderer *tmpl.Renderer) error {
-	info, err := os.Stat(contentPath)
-	if err != nil {
-		return fmt.Errorf("failed to stat content file: %w", err)
-	}
-	if info.Size() == 0 {
-		return fmt.Errorf("content file is empty: %s", contentPath)
+// buildPage renders a page and writes it to the public directory, once per
+// pager if the page is paginated. It returns the written outputs relative to
+// the public directory.
+func buildPage(entry *pageEntry, resolved *model.ResolvedConfig, site *model.Site, renderer *tmpl.Renderer) ([]string, error) {
+	pagers := entry.pagers
+	if pagers == nil {
+		pagers = []*model.Paginator{nil}
 	}
 
-	raw, err := os.ReadFile(contentPath)
-	if err != nil {
-		return fmt.Errorf("failed to read content: %w", err)
-	}
+	outputs := make([]string, 0, len(pagers))
+	for _, paginator := range pagers {
+		relPermalink := entry.relPermalink
+		if paginator != nil {
+			relPermalink = paginator.Pagers[paginator.PageNumber-1].RelPermalink
+		}
 
-	if len(raw) == 0 {
-		return fmt.Errorf("content file is empty: %s", contentPath)
-	}
+		output, err := renderer.RenderPage(site, entry.page, paginator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
 
-	fm, html, _, err := content.ParseAndRender(contentPath, raw, resolved.UnsafeHTML)
-	if err != nil {
-		return fmt.Errorf("failed to parse content: %w", err)
-	}
+		outputPath := router.OutputPath(resolved.Paths.Public, relPermalink, routerOptions(resolved))
 
-	relPath, err := filepath.Rel(resolved.Paths.Content, contentPath)
-	if err != nil {
-		return fmt.Errorf("failed to get relative path: %w", err)
-	}
-	relPermalink := router.RelPermalink(relPath, fm.Slug)
-	if relPermalink == "" {
-		relPermalink = "/"
-	}
+		if err := fsutil.WriteFileAtomic(outputPath, output); err != nil {
+			return nil, fmt.Errorf("failed to write output: %w", err)
+		}
 
-	page := model.Page{
-		Title:        fm.Title,
-		Slug:         fm.Slug,
-		Layout:       fm.Layout,
-		RelPermalink: relPermalink,
-		ContentHTML:  template.HTML(html),
-		SourcePath:   contentPath,
-	}
+		outputRel, err := filepath.Rel(resolved.Paths.Public, outputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relative output path: %w", err)
+		}
+		outputs = append(outputs, filepath.ToSlash(outputRel))
 
-	output, err := renderer.RenderPage(site, page)
-	if err != nil {
-		return fmt.Errorf("failed to render template: %w", err)
+		logx.Infof("Built: %s -> %s", entry.key, relPermalink)
 	}
 
-	outputPath := router.OutputPath(resolved.Paths.Public, relPermalink)
+	return outputs, nil
+}
 
-	if err := fsutil.WriteFileAtomic(outputPath, output); err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
+func routerOptions(resolved *model.ResolvedConfig) router.Options {
+	return router.Options{
+		PrettyURLs: resolved.PrettyURLs,
+		Permalinks: resolved.Permalinks,
+		BasePath:   resolved.BasePath,
 	}
-
-	logx.Infof("Built: %s -> %s", contentPath, relPermalink)
-
-	return nil
 }
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"sprout/internal/feed"
+	"sprout/internal/fsutil"
+	"sprout/internal/logx"
+	"sprout/internal/model"
+	"sprout/internal/router"
+)
+
+func hasFeeds(page *model.Page, cfg model.FeedsConfig) bool {
+	if len(cfg.Formats) == 0 {
+		return false
+	}
+	switch page.Kind {
+	case model.KindHome:
+		return cfg.Home
+	case model.KindSection:
+		return cfg.Sections
+	}
+	return false
+}
+
+// buildFeeds writes every configured feed for a home or section page next to
+// its index.html and returns the outputs relative to the public directory.
+// The home feed lists every regular page on the site; a section feed lists
+// the regular pages anywhere below it.
+func buildFeeds(entry *pageEntry, resolved *model.ResolvedConfig, site *model.Site, renderer *feed.Renderer) ([]string, error) {
+	page := entry.page
+
+	var items model.Pages
+	if page.Kind == model.KindHome {
+		items = site.RegularPages
+	} else {
+		items = regularDescendants(page)
+	}
+	items = items.ByDate().Reverse()
+	if limit := resolved.Feeds.Limit; limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	data := &feed.Feed{
+		Title:       page.Title,
+		Description: feedDescription(page, site),
+		Link:        router.AbsURL(resolved.BaseURL, page.RelPermalink),
+		Updated:     page.Date,
+		Items:       make([]feed.Item, 0, len(items)),
+		Site:        site,
+		Page:        page,
+	}
+	for _, item := range items {
+		if item.Date.After(data.Updated) {
+			data.Updated = item.Date
+		}
+		data.Items = append(data.Items, feed.Item{
+			Title:   item.Title,
+			Link:    router.AbsURL(resolved.BaseURL, item.RelPermalink),
+			Date:    item.Date,
+			Summary: itemSummary(item),
+			Page:    item,
+		})
+	}
+
+	outputs := make([]string, 0, len(resolved.Feeds.Formats))
+	for _, name := range resolved.Feeds.Formats {
+		format, _ := feed.Lookup(name)
+		relPermalink := router.FeedPermalink(page.RelPermalink, format.Filename)
+		data.FeedLink = router.AbsURL(resolved.BaseURL, relPermalink)
+
+		output, err := renderer.Render(format.Name, data)
+		if err != nil {
+			return nil, err
+		}
+
+		outputPath := router.OutputPath(resolved.Paths.Public, relPermalink, routerOptions(resolved))
+		if err := fsutil.WriteFileAtomic(outputPath, output); err != nil {
+			return nil, fmt.Errorf("failed to write feed: %w", err)
+		}
+
+		outputRel, err := filepath.Rel(resolved.Paths.Public, outputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relative output path: %w", err)
+		}
+		outputs = append(outputs, filepath.ToSlash(outputRel))
+
+		logx.Infof("Built: %s -> %s", entry.key, relPermalink)
+	}
+
+	return outputs, nil
+}
+
+func regularDescendants(page *model.Page) model.Pages {
+	var pages model.Pages
+	for _, child := range page.Pages {
+		if child.Kind == model.KindPage {
+			pages = append(pages, child)
+			continue
+		}
+		pages = append(pages, regularDescendants(child)...)
+	}
+	return pages
+}
+
+func feedDescription(page *model.Page, site *model.Site) string {
+	if description, ok := page.Params["description"].(string); ok {
+		return description
+	}
+	if description, ok := site.Params["description"].(string); ok {
+		return description
+	}
+	return ""
+}
+
+// itemSummary uses the page's description param when it has one and its
+// summary otherwise.
+func itemSummary(page *model.Page) string {
+	if description, ok := page.Params["description"].(string); ok {
+		return description
+	}
+	return string(page.Summary)
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"sprout/internal/highlight"
+)
+
+// GenHighlightCSS writes the stylesheet for a syntax highlighting style, for
+// sites that set classes = true in [markup.highlight].
+func GenHighlightCSS(w io.Writer, style string) error {
+	if style == "" {
+		return fmt.Errorf("missing style name; available styles: %s", strings.Join(highlight.Styles(), ", "))
+	}
+	if !highlight.StyleExists(style) {
+		return fmt.Errorf("unknown highlight style %q; available styles: %s", style, strings.Join(highlight.Styles(), ", "))
+	}
+	return highlight.WriteCSS(w, style)
+}
@@ -0,0 +1,356 @@
+package app
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"sprout/internal/cache"
+	"sprout/internal/content"
+	"sprout/internal/logx"
+	"sprout/internal/model"
+	"sprout/internal/router"
+	tmpl "sprout/internal/template"
+)
+
+// pageEntry is one page of the build: either a content file or a page Sprout
+// generates itself, such as a taxonomy listing. key identifies it in the
+// cache; for content files it is the path relative to the content directory.
+type pageEntry struct {
+	key          string
+	relPath      string
+	contentPath  string
+	raw          []byte
+	frontMatter  *model.FrontMatter
+	relPermalink string
+	page         *model.Page
+	// shortcodes lists the shortcodes used in the content, whose templates
+	// are part of the page's signature.
+	shortcodes []string
+	// refs holds the content files the content links to, with their URLs.
+	refs map[string]string
+	// pagers is nil unless the page is a paginated list.
+	pagers []*model.Paginator
+}
+
+func loadPages(resolved *model.ResolvedConfig, contentFiles []string) ([]*pageEntry, error) {
+	sorted := append([]string(nil), contentFiles...)
+	sort.Strings(sorted)
+
+	pages := make([]*pageEntry, 0, len(sorted))
+	for _, relPath := range sorted {
+		if relPath == "" {
+			continue
+		}
+		contentPath := filepath.Join(resolved.Paths.Content, relPath)
+		contentPath = filepath.Clean(contentPath)
+		if !strings.HasPrefix(contentPath, resolved.Paths.Content) {
+			return nil, fmt.Errorf("invalid content path: %s", contentPath)
+		}
+
+		page, err := loadPage(resolved, relPath, contentPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load page %s: %w", contentPath, err)
+		}
+		pages = append(pages, page)
+	}
+
+	return pages, nil
+}
+
+func loadPage(resolved *model.ResolvedConfig, relPath, contentPath string) (*pageEntry, error) {
+	raw, err := os.ReadFile(contentPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content: %w", err)
+	}
+
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("content file is empty: %s", contentPath)
+	}
+
+	fm, _, err := content.ParsePage(contentPath, raw)
+	if err != nil {
+		return nil, err
+	}
+	if fm.Slug == "" {
+		fm.Slug = content.DeriveSlug(contentPath)
+	}
+
+	relPermalink := router.RelPermalink(relPath, fm, routerOptions(resolved))
+	if relPermalink == "" {
+		relPermalink = "/"
+	}
+
+	return &pageEntry{
+		key:          relPath,
+		relPath:      relPath,
+		contentPath:  contentPath,
+		raw:          raw,
+		frontMatter:  fm,
+		relPermalink: relPermalink,
+	}, nil
+}
+
+// filterPages drops drafts, pages scheduled for the future and expired pages
+// unless the build options ask for them. Dropped pages get no cache record,
+// so anything they produced in an earlier build is pruned.
+func filterPages(pages []*pageEntry, opts BuildOptions, now time.Time) ([]*pageEntry, int) {
+	kept := make([]*pageEntry, 0, len(pages))
+	skipped := 0
+
+	for _, page := range pages {
+		if reason := unpublished(page.frontMatter, opts, now); reason != "" {
+			logx.Infof("Skipping %s, which %s", page.key, reason)
+			skipped++
+			continue
+		}
+		kept = append(kept, page)
+	}
+
+	return kept, skipped
+}
+
+// unpublished returns why filterPages drops a page, such as "is a draft",
+// or an empty string if the page is built.
+func unpublished(fm *model.FrontMatter, opts BuildOptions, now time.Time) string {
+	publishDate := fm.PublishDate
+	if publishDate.IsZero() {
+		publishDate = fm.Date
+	}
+
+	switch {
+	case fm.Draft && !opts.Drafts:
+		return "is a draft"
+	case publishDate.After(now) && !opts.Future:
+		return "is not published until " + publishDate.Format(time.RFC3339)
+	case !fm.ExpiryDate.IsZero() && !fm.ExpiryDate.After(now) && !opts.Expired:
+		return "expired on " + fm.ExpiryDate.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// resolveCollisions checks that no two pages share a permalink. Pages are
+// sorted by key, so when collisions are allowed the first page keeps
+// the URL and the others are dropped from the build.
+func resolveCollisions(pages []*pageEntry, allow bool) ([]*pageEntry, error) {
+	owners := make(map[string]*pageEntry, len(pages))
+	kept := make([]*pageEntry, 0, len(pages))
+
+	for _, page := range pages {
+		owner, exists := owners[page.relPermalink]
+		if !exists {
+			owners[page.relPermalink] = page
+			kept = append(kept, page)
+			continue
+		}
+
+		if !allow {
+			return nil, fmt.Errorf("permalink collision: %s and %s both resolve to %s", owner.key, page.key, page.relPermalink)
+		}
+		logx.Warnf("permalink collision: %s and %s both resolve to %s, skipping %s", owner.key, page.key, page.relPermalink, page.key)
+	}
+
+	return kept, nil
+}
+
+// newPage creates a content file's page from its front matter.
+func newPage(entry *pageEntry) *model.Page {
+	fm := entry.frontMatter
+	return &model.Page{
+		Kind:         pageKind(entry.relPath),
+		Title:        fm.Title,
+		Slug:         fm.Slug,
+		Layout:       fm.Layout,
+		RelPermalink: entry.relPermalink,
+		SourcePath:   entry.contentPath,
+		Date:         fm.Date,
+		PublishDate:  fm.PublishDate,
+		ExpiryDate:   fm.ExpiryDate,
+		Draft:        fm.Draft,
+		Weight:       fm.Weight,
+		Params:       fm.Params,
+		Section:      pageSection(entry.relPath),
+	}
+}
+
+func renderContent(entry *pageEntry, resolved *model.ResolvedConfig, renderer *tmpl.Renderer, refs *refs) error {
+	// The page is created from the front matter first so that shortcodes
+	// and render hooks can use it as .Page while the content is rendered.
+	fm := entry.frontMatter
+	page := newPage(entry)
+
+	opts := contentOptions(resolved)
+	markdown, err := opts.Markdown.Override(fm.Markup)
+	if err != nil {
+		return fmt.Errorf("failed to parse front matter: %w", err)
+	}
+	opts.Markdown = markdown
+	opts.RenderShortcode = func(sc *model.Shortcode) (template.HTML, error) {
+		sc.Page = page
+		return renderer.RenderShortcode(sc)
+	}
+	opts.RenderHook = func(name string, ctx *model.HookContext) (template.HTML, bool, error) {
+		ctx.Page = page
+		return renderer.RenderHook(name, ctx)
+	}
+	opts.ResolveLink = func(target string) (string, error) {
+		return refs.resolve(entry.key, sourceDir(entry.relPath), target)
+	}
+
+	refs.used = make(map[string]string)
+	defer func() { refs.used = nil }()
+
+	result, err := content.ParseAndRender(entry.contentPath, entry.raw, opts)
+	if err != nil {
+		return fmt.Errorf("failed to parse content: %w", err)
+	}
+
+	page.Title = result.FrontMatter.Title
+	page.ContentHTML = template.HTML(result.HTML)
+	page.TableOfContents = result.TableOfContents
+	page.Headings = result.Headings
+	page.Summary = result.Summary
+	page.Truncated = result.Truncated
+	page.WordCount = result.WordCount
+	page.ReadingTime = result.ReadingTime
+
+	entry.page = page
+	entry.shortcodes = result.Shortcodes
+	entry.refs = refs.used
+	return nil
+}
+
+// contentRecord returns the rendered content of a page for the cache.
+func contentRecord(entry *pageEntry, signature string) cache.ContentRecord {
+	page := entry.page
+	return cache.ContentRecord{
+		Signature:       signature,
+		Title:           page.Title,
+		HTML:            string(page.ContentHTML),
+		TableOfContents: string(page.TableOfContents),
+		Headings:        page.Headings,
+		Summary:         string(page.Summary),
+		Truncated:       page.Truncated,
+		WordCount:       page.WordCount,
+		ReadingTime:     page.ReadingTime,
+		Shortcodes:      entry.shortcodes,
+		Refs:            entry.refs,
+	}
+}
+
+// restoreContent creates a page with the content rendered by an earlier
+// build.
+func restoreContent(entry *pageEntry, record cache.ContentRecord) {
+	page := newPage(entry)
+	page.Title = record.Title
+	page.ContentHTML = template.HTML(record.HTML)
+	page.TableOfContents = template.HTML(record.TableOfContents)
+	page.Headings = record.Headings
+	page.Summary = template.HTML(record.Summary)
+	page.Truncated = record.Truncated
+	page.WordCount = record.WordCount
+	page.ReadingTime = record.ReadingTime
+
+	entry.page = page
+	entry.shortcodes = record.Shortcodes
+	entry.refs = record.Refs
+}
+
+func contentOptions(resolved *model.ResolvedConfig) content.Options {
+	return content.Options{
+		UnsafeHTML:    resolved.UnsafeHTML,
+		Markdown:      resolved.Markup.MarkdownConfig,
+		TOCStartLevel: resolved.Markup.TOC.StartLevel,
+		TOCEndLevel:   resolved.Markup.TOC.EndLevel,
+		SummaryLength: resolved.SummaryLength,
+		Highlight:     resolved.Markup.Highlight,
+	}
+}
+
+func pageKind(relPath string) string {
+	if !isIndexFile(relPath) {
+		return model.KindPage
+	}
+	if sourceDir(relPath) == "" {
+		return model.KindHome
+	}
+	return model.KindSection
+}
+
+func pageSection(relPath string) string {
+	dir := sourceDir(relPath)
+	if idx := strings.Index(dir, "/"); idx != -1 {
+		return dir[:idx]
+	}
+	return dir
+}
+
+func isIndexFile(relPath string) bool {
+	name := path.Base(filepath.ToSlash(relPath))
+	return strings.TrimSuffix(name, path.Ext(name)) == "index"
+}
+
+func sourceDir(relPath string) string {
+	dir := path.Dir(filepath.ToSlash(relPath))
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// linkPages connects every page to the nearest index page above it, which
+// becomes its .Parent and lists it in .Pages. It returns all pages in the
+// default order.
+func linkPages(entries []*pageEntry) model.Pages {
+	lists := make(map[string]*model.Page)
+	for _, entry := range entries {
+		if entry.page.Kind != model.KindPage {
+			lists[sourceDir(entry.relPath)] = entry.page
+		}
+	}
+
+	pages := make(model.Pages, 0, len(entries))
+	for _, entry := range entries {
+		page := entry.page
+		pages = append(pages, page)
+
+		if page.Kind == model.KindHome {
+			continue
+		}
+
+		dir := sourceDir(entry.relPath)
+		if page.Kind == model.KindSection {
+			dir = parentDir(dir)
+		}
+		for {
+			if parent, ok := lists[dir]; ok {
+				page.Parent = parent
+				parent.Pages = append(parent.Pages, page)
+				break
+			}
+			if dir == "" {
+				break
+			}
+			dir = parentDir(dir)
+		}
+	}
+
+	for _, page := range lists {
+		page.Pages = page.Pages.ByWeight()
+	}
+
+	return pages.ByWeight()
+}
+
+func parentDir(dir string) string {
+	parent := path.Dir(dir)
+	if parent == "." || parent == "/" {
+		return ""
+	}
+	return parent
+}
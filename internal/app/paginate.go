@@ -0,0 +1,93 @@
+package app
+
+import (
+	"fmt"
+
+	"sprout/internal/logx"
+	"sprout/internal/model"
+	"sprout/internal/router"
+)
+
+// paginators splits the pages listed by a home, section, taxonomy or term
+// page into pages of the configured size. A section's own paginate front
+// matter overrides the site setting. It returns nil when the page isn't
+// paginated, and a single paginator when everything fits on one page.
+func paginators(entry *pageEntry, resolved *model.ResolvedConfig) []*model.Paginator {
+	page := entry.page
+	if page.Kind == model.KindPage {
+		return nil
+	}
+
+	size := resolved.Paginate
+	if entry.frontMatter != nil && entry.frontMatter.Paginate > 0 {
+		size = entry.frontMatter.Paginate
+	}
+	if size <= 0 {
+		return nil
+	}
+
+	total := (len(page.Pages) + size - 1) / size
+	if total == 0 {
+		total = 1
+	}
+
+	opts := routerOptions(resolved)
+	pagers := make([]model.Pager, total)
+	for i := range pagers {
+		pagers[i] = model.Pager{
+			PageNumber:   i + 1,
+			RelPermalink: router.PagerPermalink(page.RelPermalink, i+1, opts),
+		}
+	}
+
+	result := make([]*model.Paginator, total)
+	for i := range result {
+		start := i * size
+		end := min(start+size, len(page.Pages))
+		result[i] = &model.Paginator{
+			Pages:      page.Pages[start:end],
+			PageNumber: i + 1,
+			PageSize:   size,
+			TotalPages: total,
+			TotalItems: len(page.Pages),
+			Pagers:     pagers,
+		}
+	}
+
+	return result
+}
+
+// paginate sets the paginators of every list page and checks that the URLs
+// of pages 2 and up don't collide with another page's, such as a content
+// file at blog/page/2.md. When collisions are allowed, the other page keeps
+// the URL and the pager is skipped.
+func paginate(entries []*pageEntry, resolved *model.ResolvedConfig, allow bool) error {
+	owners := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		owners[entry.relPermalink] = entry.key
+	}
+
+	for _, entry := range entries {
+		var kept []*model.Paginator
+		for _, paginator := range paginators(entry, resolved) {
+			if paginator.PageNumber == 1 {
+				kept = append(kept, paginator)
+				continue
+			}
+			pager := fmt.Sprintf("page %d of %s", paginator.PageNumber, entry.key)
+			relPermalink := paginator.Pagers[paginator.PageNumber-1].RelPermalink
+			if owner, exists := owners[relPermalink]; exists {
+				if !allow {
+					return fmt.Errorf("permalink collision: %s and %s both resolve to %s", owner, pager, relPermalink)
+				}
+				logx.Warnf("permalink collision: %s and %s both resolve to %s, skipping %s", owner, pager, relPermalink, pager)
+				continue
+			}
+			owners[relPermalink] = pager
+			kept = append(kept, paginator)
+		}
+		entry.pagers = kept
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sprout/internal/cache"
+	"sprout/internal/logx"
+)
+
+func pruneOutputs(publicDir string, oldPages, newPages map[string]cache.PageRecord) (int, error) {
+	current := make(map[string]bool)
+	for _, record := range newPages {
+		for _, output := range record.Outputs {
+			current[output] = true
+		}
+	}
+
+	pruned := 0
+	for source, record := range oldPages {
+		for _, output := range record.Outputs {
+			if current[output] {
+				continue
+			}
+			if output == "" || strings.Contains(output, "..") {
+				continue
+			}
+
+			outputPath := filepath.Join(publicDir, filepath.FromSlash(output))
+			if err := os.Remove(outputPath); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return pruned, err
+			}
+			removeEmptyDirs(publicDir, filepath.Dir(outputPath))
+
+			logx.Infof("Pruned: %s (from %s)", output, source)
+			pruned++
+		}
+	}
+
+	return pruned, nil
+}
+
+func removeEmptyDirs(publicDir, dir string) {
+	for dir != publicDir && strings.HasPrefix(dir, publicDir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
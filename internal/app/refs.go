@@ -0,0 +1,86 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"sprout/internal/logx"
+)
+
+// refs maps content files to their permalinks, so that Markdown links and
+// the ref template function can point at source files instead of URLs.
+type refs struct {
+	permalinks map[string]string
+	// skipped holds why content files that exist aren't built, such as
+	// "is a draft".
+	skipped map[string]string
+	// warn leaves references to missing files as they are instead of
+	// failing the build.
+	warn bool
+	// used collects the files referred to while a page's content is
+	// rendered, with the URLs they resolved to, for the content cache.
+	used map[string]string
+}
+
+// newRefs maps the built pages to their permalinks. Loaded pages that aren't
+// built are kept with the reason skipReason gives, so that links to them
+// explain why they are broken.
+func newRefs(loaded, built []*pageEntry, brokenRefs string, skipReason func(entry *pageEntry) string) *refs {
+	r := &refs{
+		permalinks: make(map[string]string, len(built)),
+		skipped:    make(map[string]string),
+		warn:       brokenRefs == "warn",
+	}
+	for _, entry := range built {
+		r.permalinks[filepath.ToSlash(entry.relPath)] = entry.relPermalink
+	}
+	for _, entry := range loaded {
+		name := filepath.ToSlash(entry.relPath)
+		if _, ok := r.permalinks[name]; !ok {
+			r.skipped[name] = skipReason(entry)
+		}
+	}
+	return r
+}
+
+// resolve returns the permalink of the content file target. A relative
+// target is relative to dir, the directory of the page that refers to it;
+// one starting with a slash is relative to the content directory.
+func (r *refs) resolve(from, dir, target string) (string, error) {
+	name := target
+	if !strings.HasPrefix(target, "/") {
+		name = path.Join(dir, target)
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	permalink, ok := r.permalinks[name]
+	if r.used != nil {
+		r.used[name] = permalink
+	}
+	if ok {
+		return permalink, nil
+	}
+
+	err := fmt.Errorf("no content file %s", name)
+	if reason, ok := r.skipped[name]; ok {
+		err = fmt.Errorf("%s %s", name, reason)
+	}
+	if !r.warn {
+		return "", err
+	}
+	logx.Warnf("%s: broken reference to %q: %v", from, target, err)
+	return target, nil
+}
+
+// unchanged reports whether the files in used, as recorded by an earlier
+// build, still have the same URLs.
+func (r *refs) unchanged(used map[string]string) bool {
+	for name, permalink := range used {
+		if r.permalinks[name] != permalink {
+			return false
+		}
+	}
+	return true
+}
@@ -22,7 +22,7 @@ const (
 	RebuildWatch   RebuildMode = "watch"
 )
 
-func Serve(root string, port int, rebuildMode RebuildMode, livereload bool) error {
+func Serve(root string, port int, rebuildMode RebuildMode, livereload bool, buildOpts BuildOptions) error {
 	cfg, err := config.Load(root)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
@@ -33,31 +33,36 @@ func Serve(root string, port int, rebuildMode RebuildMode, livereload bool) erro
 		return fmt.Errorf("failed to resolve config: %w", err)
 	}
 
+	// Cleaning only makes sense for the initial build; rebuilds stay incremental.
+	initialOpts := buildOpts
+	buildOpts.Clean = false
+
 	if rebuildMode != RebuildManual {
 		logx.Infof("Building site...")
-		if _, err := Build(root, false); err != nil {
+		if _, err := Build(root, initialOpts); err != nil {
 			return fmt.Errorf("initial build failed: %w", err)
 		}
 	}
 
+	var lr *httpd.LiveReload
+	if livereload {
+		lr = httpd.NewLiveReload()
+	}
+
 	var rebuildFunc func() error
 	switch rebuildMode {
 	case RebuildManual:
 		rebuildFunc = nil
 	case RebuildRequest:
-		rebuildFunc = createRequestRebuildFunc(root, resolved)
+		rebuildFunc = createRequestRebuildFunc(root, resolved, buildOpts)
 	case RebuildWatch:
-		return fmt.Errorf("watch mode not yet implemented")
+		go newWatcher(root, resolved, lr, buildOpts).run()
 	default:
 		return fmt.Errorf("unknown rebuild mode: %s", rebuildMode)
 	}
 
-	var lr *httpd.LiveReload
-	if livereload {
-		lr = httpd.NewLiveReload()
-	}
-
 	baseHandler := httpd.NewServer(resolved.Paths.Public)
+	baseHandler.BasePath = resolved.BasePath
 	baseHandler.LiveReload = lr
 	baseHandler.LivereloadPort = port
 
@@ -74,7 +79,7 @@ func Serve(root string, port int, rebuildMode RebuildMode, livereload bool) erro
 	}
 
 	addr := fmt.Sprintf(":%d", port)
-	logx.Infof("Serving on http://localhost%d", port)
+	logx.Infof("Serving on http://localhost:%d%s/", port, resolved.BasePath)
 
 	return http.ListenAndServe(addr, handler)
 }
@@ -136,12 +141,13 @@ func checkSourcesChanged(root string, resolved *model.ResolvedConfig) (bool, err
 	}
 
 	plan := cache.Diff(oldCache, snapshot)
-	return len(plan.PagesToRebuild) > 0 || len(plan.AssetsToCopy) > 0 || plan.TemplatesChanged, nil
+	return len(plan.PagesToRebuild) > 0 || len(plan.PagesRemoved) > 0 || len(plan.AssetsToCopy) > 0 ||
+		plan.TemplatesChanged || plan.ShortcodesChanged || plan.ConfigChanged, nil
 }
 
-func createRequestRebuildFunc(root string, resolved *model.ResolvedConfig) func() error {
+func createRequestRebuildFunc(root string, resolved *model.ResolvedConfig, buildOpts BuildOptions) func() error {
 	return func() error {
-		_, err := Build(root, false)
+		_, err := Build(root, buildOpts)
 		return err
 	}
 }
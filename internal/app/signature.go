@@ -0,0 +1,49 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"sprout/internal/cache"
+)
+
+// siteSignature fingerprints everything the pages' output depends on
+// besides the config and layout templates, which the cache diff already
+// tracks: the source file, URL and shortcode templates of every content page
+// being built. Any template can list .Site.Pages or .Site.Taxonomies and
+// show another page's terms, params, summary or content, and any page can
+// link to another by its source file, so every page shares the signature and
+// is rebuilt when it differs from the one recorded in the cache. A page's
+// Markdown is only rendered again when its own contentSignature changes.
+func siteSignature(entries []*pageEntry, files, templates map[string]cache.FileEntry) string {
+	h := sha256.New()
+	for _, entry := range entries {
+		if entry.relPath != "" {
+			writeEntry(h, entry, files, templates)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// contentSignature fingerprints what a page's rendered content depends on
+// besides the config and render hooks: its source file and URL and the
+// templates of the shortcodes it uses.
+func contentSignature(entry *pageEntry, files, templates map[string]cache.FileEntry) string {
+	h := sha256.New()
+	writeEntry(h, entry, files, templates)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeEntry(h hash.Hash, entry *pageEntry, files, templates map[string]cache.FileEntry) {
+	if entry == nil {
+		return
+	}
+	file := files[entry.relPath]
+	fmt.Fprintf(h, "%s\x00%d\x00%d\x00%s\n", entry.key, file.MTime, file.Size, entry.relPermalink)
+	for _, name := range entry.shortcodes {
+		file := templates[cache.ShortcodesDir+name+".html"]
+		fmt.Fprintf(h, "shortcode\x00%s\x00%d\x00%d\n", name, file.MTime, file.Size)
+	}
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"sprout/internal/cache"
+	"sprout/internal/fsutil"
+	"sprout/internal/logx"
+	"sprout/internal/model"
+	"sprout/internal/router"
+	"sprout/internal/sitemap"
+)
+
+const sitemapKey = "sitemap"
+
+// sitemapFiles returns the files Sprout generates for the sitemap. A
+// sitemap.xml or robots.txt in the static directory replaces the generated
+// one.
+func sitemapFiles(static map[string]cache.FileEntry) []string {
+	var files []string
+	for _, name := range []string{sitemap.SitemapFile, sitemap.RobotsFile} {
+		if _, exists := static[name]; !exists {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// sitemapData lists every built page except drafts and pages with
+// sitemap = false in their front matter. lastmod is the page date, or the
+// source file's modification time for undated content pages.
+func sitemapData(entries []*pageEntry, resolved *model.ResolvedConfig, site *model.Site, files map[string]cache.FileEntry) *sitemap.Sitemap {
+	data := &sitemap.Sitemap{
+		SitemapLink: router.AbsURL(resolved.BaseURL, router.RelURL(sitemap.SitemapFile, routerOptions(resolved))),
+		Site:        site,
+	}
+
+	for _, entry := range entries {
+		page := entry.page
+		if page.Draft {
+			continue
+		}
+		if include, ok := page.Params["sitemap"].(bool); ok && !include {
+			continue
+		}
+
+		lastMod := page.Date
+		if lastMod.IsZero() && entry.relPath != "" {
+			if file, ok := files[entry.relPath]; ok {
+				lastMod = time.Unix(0, file.MTime)
+			}
+		}
+
+		data.Entries = append(data.Entries, sitemap.Entry{
+			Loc:     router.AbsURL(resolved.BaseURL, page.RelPermalink),
+			LastMod: lastMod,
+			Page:    page,
+		})
+	}
+
+	return data
+}
+
+func sitemapSignature(data *sitemap.Sitemap, files []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%v\n", files)
+	for _, entry := range data.Entries {
+		fmt.Fprintf(h, "%s\x00%s\n", entry.Loc, entry.LastMod.Format(time.RFC3339Nano))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// buildSitemap writes the given files (sitemap.xml and robots.txt) to the
+// public directory.
+func buildSitemap(data *sitemap.Sitemap, files []string, resolved *model.ResolvedConfig, renderer *sitemap.Renderer) error {
+	for _, name := range files {
+		output, err := renderer.Render(name, data)
+		if err != nil {
+			return err
+		}
+
+		outputPath := filepath.Join(resolved.Paths.Public, name)
+		if err := fsutil.WriteFileAtomic(outputPath, output); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+
+		logx.Infof("Built: %s", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"sprout/internal/content"
+	"sprout/internal/logx"
+	"sprout/internal/model"
+	"sprout/internal/router"
+)
+
+// buildTaxonomies groups content pages by the terms listed in their front
+// matter and creates a listing page for each taxonomy (/tags/) and each term
+// (/tags/go/). Taxonomies without any terms produce no pages.
+func buildTaxonomies(resolved *model.ResolvedConfig, entries []*pageEntry) ([]*pageEntry, map[string]*model.Taxonomy) {
+	singulars := make(map[string]string, len(resolved.Taxonomies))
+	names := make([]string, 0, len(resolved.Taxonomies))
+	for singular, plural := range resolved.Taxonomies {
+		if plural == "" {
+			continue
+		}
+		singulars[plural] = singular
+		names = append(names, plural)
+	}
+	sort.Strings(names)
+
+	opts := routerOptions(resolved)
+	taxonomies := make(map[string]*model.Taxonomy, len(names))
+	var generated []*pageEntry
+
+	for _, name := range names {
+		terms := make(map[string]*model.Page)
+		var slugs []string
+
+		for _, entry := range entries {
+			values, err := termValues(entry.page.Params[name])
+			if err != nil {
+				logx.Warnf("%s: %s: %v", entry.key, name, err)
+				continue
+			}
+			for _, value := range values {
+				slug := content.Slugify(value)
+				if slug == "" {
+					continue
+				}
+				term, exists := terms[slug]
+				if !exists {
+					term = &model.Page{
+						Kind:         model.KindTerm,
+						Title:        value,
+						Slug:         slug,
+						RelPermalink: router.TermPermalink(name, slug, opts),
+						Section:      name,
+						Params:       make(map[string]any),
+					}
+					terms[slug] = term
+					slugs = append(slugs, slug)
+				}
+				if !containsPage(term.Pages, entry.page) {
+					term.Pages = append(term.Pages, entry.page)
+				}
+				if entry.page.Date.After(term.Date) {
+					term.Date = entry.page.Date
+				}
+			}
+		}
+
+		if len(terms) == 0 {
+			continue
+		}
+
+		list := &model.Page{
+			Kind:         model.KindTaxonomy,
+			Title:        capitalize(name),
+			Slug:         name,
+			RelPermalink: router.TaxonomyPermalink(name, opts),
+			Section:      name,
+			Params:       make(map[string]any),
+		}
+		generated = append(generated, &pageEntry{
+			key:          "taxonomy:" + name,
+			relPermalink: list.RelPermalink,
+			page:         list,
+		})
+
+		sort.Strings(slugs)
+		for _, slug := range slugs {
+			term := terms[slug]
+			term.Parent = list
+			term.Pages = term.Pages.ByWeight()
+			list.Pages = append(list.Pages, term)
+			if term.Date.After(list.Date) {
+				list.Date = term.Date
+			}
+			generated = append(generated, &pageEntry{
+				key:          "term:" + name + "/" + slug,
+				relPermalink: term.RelPermalink,
+				page:         term,
+			})
+		}
+		list.Pages = list.Pages.ByTitle()
+
+		taxonomies[name] = &model.Taxonomy{
+			Name:         name,
+			Singular:     singulars[name],
+			RelPermalink: list.RelPermalink,
+			Terms:        list.Pages,
+		}
+	}
+
+	return generated, taxonomies
+}
+
+func termValues(value any) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("terms must be strings, got %T", item)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("expected a string or a list of strings, got %T", value)
+}
+
+func containsPage(pages model.Pages, page *model.Page) bool {
+	for _, p := range pages {
+		if p == page {
+			return true
+		}
+	}
+	return false
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
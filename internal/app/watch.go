@@ -0,0 +1,88 @@
+package app
+
+import (
+	"path/filepath"
+	"time"
+
+	"sprout/internal/cache"
+	"sprout/internal/httpd"
+	"sprout/internal/logx"
+	"sprout/internal/model"
+)
+
+const (
+	watchInterval = 250 * time.Millisecond
+	watchDebounce = 300 * time.Millisecond
+)
+
+type watcher struct {
+	root       string
+	resolved   *model.ResolvedConfig
+	liveReload *httpd.LiveReload
+	buildOpts  BuildOptions
+	interval   time.Duration
+	debounce   time.Duration
+}
+
+func newWatcher(root string, resolved *model.ResolvedConfig, lr *httpd.LiveReload, buildOpts BuildOptions) *watcher {
+	return &watcher{
+		root:       root,
+		resolved:   resolved,
+		liveReload: lr,
+		buildOpts:  buildOpts,
+		interval:   watchInterval,
+		debounce:   watchDebounce,
+	}
+}
+
+func (w *watcher) snapshot() (*cache.Snapshot, error) {
+	configPath := filepath.Join(w.root, "sprout.toml")
+	return cache.CreateSnapshotWithConfig(w.resolved.Paths.Content, w.resolved.Paths.Templates, w.resolved.Paths.Static, configPath)
+}
+
+func (w *watcher) run() {
+	last, err := w.snapshot()
+	if err != nil {
+		logx.Errorf("Failed to snapshot sources: %v", err)
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	pending := false
+	var lastChange time.Time
+
+	for range ticker.C {
+		current, err := w.snapshot()
+		if err != nil {
+			// Files can disappear between the walk and the stat while an
+			// editor is saving; try again on the next tick.
+			logx.Infof("Failed to snapshot sources: %v", err)
+			continue
+		}
+
+		if !cache.SnapshotsEqual(last, current) {
+			last = current
+			lastChange = time.Now()
+			pending = true
+			continue
+		}
+
+		if !pending || time.Since(lastChange) < w.debounce {
+			continue
+		}
+		pending = false
+
+		logx.Infof("Sources changed, rebuilding...")
+		start := time.Now()
+		if _, err := Build(w.root, w.buildOpts); err != nil {
+			logx.Errorf("Rebuild failed: %v", err)
+			continue
+		}
+		logx.Infof("Rebuilt in %v", time.Since(start))
+
+		if w.liveReload != nil {
+			w.liveReload.Reload()
+		}
+	}
+}
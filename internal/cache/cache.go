@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"sprout/internal/fsutil"
+	"sprout/internal/model"
 )
 
 type FileEntry struct {
@@ -14,12 +15,37 @@ type FileEntry struct {
 	Size  int64 `json:"size"`
 }
 
+type PageRecord struct {
+	Outputs   []string `json:"outputs"`
+	Signature string   `json:"signature,omitempty"`
+}
+
+// ContentRecord is a content file's rendered Markdown. It is reused until the
+// file, the shortcode templates it uses or the pages it links to change.
+type ContentRecord struct {
+	Signature       string          `json:"signature"`
+	Title           string          `json:"title,omitempty"`
+	HTML            string          `json:"html"`
+	TableOfContents string          `json:"toc,omitempty"`
+	Headings        []model.Heading `json:"headings,omitempty"`
+	Summary         string          `json:"summary,omitempty"`
+	Truncated       bool            `json:"truncated,omitempty"`
+	WordCount       int             `json:"word_count"`
+	ReadingTime     int             `json:"reading_time"`
+	Shortcodes      []string        `json:"shortcodes,omitempty"`
+	// Refs maps the content files the page links to, relative to the
+	// content directory, to the URLs they had.
+	Refs map[string]string `json:"refs,omitempty"`
+}
+
 type Cache struct {
-	ContentFiles  map[string]FileEntry `json:"content_files"`
-	TemplateFiles map[string]FileEntry `json:"template_files"`
-	StaticFiles   map[string]FileEntry `json:"static_files"`
-	ConfigFile    *FileEntry           `json:"config_file,omitempty"`
-	BuildTime     time.Time            `json:"build_time"`
+	ContentFiles  map[string]FileEntry     `json:"content_files"`
+	TemplateFiles map[string]FileEntry     `json:"template_files"`
+	StaticFiles   map[string]FileEntry     `json:"static_files"`
+	ConfigFile    *FileEntry               `json:"config_file,omitempty"`
+	Pages         map[string]PageRecord    `json:"pages,omitempty"`
+	Content       map[string]ContentRecord `json:"content,omitempty"`
+	BuildTime     time.Time                `json:"build_time"`
 }
 
 type Snapshot struct {
@@ -31,8 +57,14 @@ type Snapshot struct {
 
 type Plan struct {
 	PagesToRebuild  []string
+	PagesRemoved    []string
 	AssetsToCopy    []string
 	TemplatesChanged bool
+	ConfigChanged    bool
+	// ShortcodesChanged is set when a shortcode template was added, changed
+	// or removed. It doesn't imply TemplatesChanged: the page signatures
+	// decide which pages are rebuilt.
+	ShortcodesChanged bool
 }
 
 func LoadCache(path string) (*Cache, error) {
@@ -42,6 +74,8 @@ func LoadCache(path string) (*Cache, error) {
 			ContentFiles:  make(map[string]FileEntry),
 			TemplateFiles: make(map[string]FileEntry),
 			StaticFiles:   make(map[string]FileEntry),
+			Pages:         make(map[string]PageRecord),
+			Content:       make(map[string]ContentRecord),
 		}, nil
 	}
 	if err != nil {
@@ -62,6 +96,12 @@ func LoadCache(path string) (*Cache, error) {
 	if cache.StaticFiles == nil {
 		cache.StaticFiles = make(map[string]FileEntry)
 	}
+	if cache.Pages == nil {
+		cache.Pages = make(map[string]PageRecord)
+	}
+	if cache.Content == nil {
+		cache.Content = make(map[string]ContentRecord)
+	}
 
 	return &cache, nil
 }
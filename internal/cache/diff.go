@@ -3,10 +3,15 @@ package cache
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"sprout/internal/fsutil"
 )
 
+// ShortcodesDir holds shortcode templates, relative to the templates
+// directory.
+const ShortcodesDir = "shortcodes/"
+
 func CreateSnapshot(contentDir, templatesDir, staticDir string) (*Snapshot, error) {
 	snapshot := &Snapshot{
 		ContentFiles:  make(map[string]FileEntry),
@@ -73,6 +78,7 @@ func snapshotDir(dir string, entries map[string]FileEntry) error {
 func Diff(cache *Cache, snapshot *Snapshot) *Plan {
 	plan := &Plan{
 		PagesToRebuild:  []string{},
+		PagesRemoved:     []string{},
 		AssetsToCopy:     []string{},
 		TemplatesChanged: false,
 	}
@@ -89,21 +95,33 @@ func Diff(cache *Cache, snapshot *Snapshot) *Plan {
 		configChanged = true
 	}
 
+	// Shortcode templates only affect the pages that use them, which the
+	// page signatures track.
 	for path, entry := range snapshot.TemplateFiles {
 		oldEntry, exists := cache.TemplateFiles[path]
-		if !exists || oldEntry.MTime != entry.MTime || oldEntry.Size != entry.Size {
+		if exists && oldEntry.MTime == entry.MTime && oldEntry.Size == entry.Size {
+			continue
+		}
+		if strings.HasPrefix(path, ShortcodesDir) {
+			plan.ShortcodesChanged = true
+		} else {
 			plan.TemplatesChanged = true
-			break
 		}
 	}
 
 	for path := range cache.TemplateFiles {
-		if _, exists := snapshot.TemplateFiles[path]; !exists {
+		if _, exists := snapshot.TemplateFiles[path]; exists {
+			continue
+		}
+		if strings.HasPrefix(path, ShortcodesDir) {
+			plan.ShortcodesChanged = true
+		} else {
 			plan.TemplatesChanged = true
-			break
 		}
 	}
 
+	plan.ConfigChanged = configChanged
+
 	// If config or templates changed, rebuild all pages
 	if plan.TemplatesChanged || configChanged {
 		for path := range snapshot.ContentFiles {
@@ -118,6 +136,12 @@ func Diff(cache *Cache, snapshot *Snapshot) *Plan {
 		}
 	}
 
+	for path := range cache.ContentFiles {
+		if _, exists := snapshot.ContentFiles[path]; !exists {
+			plan.PagesRemoved = append(plan.PagesRemoved, path)
+		}
+	}
+
 	for path, entry := range snapshot.StaticFiles {
 		oldEntry, exists := cache.StaticFiles[path]
 		if !exists || oldEntry.MTime != entry.MTime || oldEntry.Size != entry.Size {
@@ -133,3 +157,31 @@ func Diff(cache *Cache, snapshot *Snapshot) *Plan {
 
 	return plan
 }
+
+func SnapshotsEqual(a, b *Snapshot) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if !entriesEqual(a.ContentFiles, b.ContentFiles) ||
+		!entriesEqual(a.TemplateFiles, b.TemplateFiles) ||
+		!entriesEqual(a.StaticFiles, b.StaticFiles) {
+		return false
+	}
+	if a.ConfigFile == nil || b.ConfigFile == nil {
+		return a.ConfigFile == nil && b.ConfigFile == nil
+	}
+	return *a.ConfigFile == *b.ConfigFile
+}
+
+func entriesEqual(a, b map[string]FileEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, entry := range a {
+		other, exists := b[path]
+		if !exists || other != entry {
+			return false
+		}
+	}
+	return true
+}
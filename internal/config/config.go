@@ -2,9 +2,13 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"sprout/internal/feed"
+	"sprout/internal/highlight"
 	"sprout/internal/model"
 
 	"github.com/pelletier/go-toml/v2"
@@ -22,9 +26,20 @@ func Load(root string) (*model.Config, error) {
 	cfg.Paths.Templates = "templates"
 	cfg.Paths.Static = "static"
 	cfg.Paths.Public = "public"
+	cfg.Feeds = model.FeedsConfig{
+		Formats:  []string{"rss", "atom", "json"},
+		Limit:    20,
+		Home:     true,
+		Sections: true,
+	}
+	cfg.Markup.TOC = model.TOCConfig{StartLevel: 2, EndLevel: 3}
+	cfg.Markup.Highlight = model.HighlightConfig{Style: "monokai"}
+	cfg.SummaryLength = 70
+	cfg.BrokenRefs = "error"
 
 	data, err := os.ReadFile(configPath)
 	if os.IsNotExist(err) {
+		cfg.Taxonomies = defaultTaxonomies()
 		return cfg, nil
 	}
 	if err != nil {
@@ -35,14 +50,42 @@ func Load(root string) (*model.Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	// An empty [taxonomies] table disables taxonomies; only a missing table
+	// falls back to the defaults.
+	if cfg.Taxonomies == nil {
+		var raw map[string]any
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+		if _, exists := raw["taxonomies"]; exists {
+			cfg.Taxonomies = map[string]string{}
+		} else {
+			cfg.Taxonomies = defaultTaxonomies()
+		}
+	}
+
 	return cfg, nil
 }
 
 func Resolve(cfg *model.Config, root string) (*model.ResolvedConfig, error) {
+	baseURL, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base_url %q: %w", cfg.BaseURL, err)
+	}
+
 	resolved := &model.ResolvedConfig{
-		BaseURL:    cfg.BaseURL,
-		PrettyURLs: cfg.PrettyURLs,
-		UnsafeHTML: cfg.UnsafeHTML,
+		BaseURL:       cfg.BaseURL,
+		BasePath:      strings.TrimSuffix(baseURL.Path, "/"),
+		PrettyURLs:    cfg.PrettyURLs,
+		UnsafeHTML:    cfg.UnsafeHTML,
+		Permalinks:    cfg.Permalinks,
+		Params:        cfg.Params,
+		Taxonomies:    cfg.Taxonomies,
+		Paginate:      cfg.Paginate,
+		Feeds:         cfg.Feeds,
+		Markup:        cfg.Markup,
+		SummaryLength: cfg.SummaryLength,
+		BrokenRefs:    cfg.BrokenRefs,
 		Paths: model.Paths{
 			Content:   filepath.Join(root, cfg.Paths.Content),
 			Templates: filepath.Join(root, cfg.Paths.Templates),
@@ -51,9 +94,39 @@ func Resolve(cfg *model.Config, root string) (*model.ResolvedConfig, error) {
 		},
 	}
 
+	toc := cfg.Markup.TOC
+	if toc.StartLevel < 1 || toc.EndLevel > 6 || toc.StartLevel > toc.EndLevel {
+		return nil, fmt.Errorf("invalid [markup.toc] levels %d to %d: expected 1 <= start_level <= end_level <= 6", toc.StartLevel, toc.EndLevel)
+	}
+
+	if style := cfg.Markup.Highlight.Style; !highlight.StyleExists(style) {
+		return nil, fmt.Errorf("unknown style %q in [markup.highlight]: run `sprout gen highlight-css` for the list of styles", style)
+	}
+
+	if cfg.SummaryLength < 1 {
+		return nil, fmt.Errorf("invalid summary_length %d: must be at least 1", cfg.SummaryLength)
+	}
+
+	if cfg.BrokenRefs != "error" && cfg.BrokenRefs != "warn" {
+		return nil, fmt.Errorf("invalid broken_refs %q: expected error or warn", cfg.BrokenRefs)
+	}
+
+	for _, name := range cfg.Feeds.Formats {
+		if _, ok := feed.Lookup(name); !ok {
+			return nil, fmt.Errorf("unknown feed format %q in [feeds]: expected rss, atom or json", name)
+		}
+	}
+
 	if err := os.MkdirAll(resolved.Paths.Public, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create public directory: %w", err)
 	}
 
 	return resolved, nil
 }
+
+func defaultTaxonomies() map[string]string {
+	return map[string]string{
+		"tag":      "tags",
+		"category": "categories",
+	}
+}
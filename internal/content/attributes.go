@@ -0,0 +1,57 @@
+package content
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// blockAttributes applies a {#id .class key=value} line to the block it
+// follows. goldmark reads such a line as the last line of the paragraph
+// above it, or as a paragraph of its own after other blocks such as tables.
+// It runs before inline parsing, so the line never becomes text.
+type blockAttributes struct{}
+
+func (blockAttributes) Transform(p *ast.Paragraph, reader text.Reader, pc parser.Context) {
+	lines := p.Lines()
+	if lines.Len() == 0 {
+		return
+	}
+	last := lines.At(lines.Len() - 1)
+	attrs, ok := attributeLine(last.Value(reader.Source()))
+	if !ok {
+		return
+	}
+
+	var target ast.Node = p
+	if lines.Len() == 1 {
+		target = p.PreviousSibling()
+		if target == nil {
+			return
+		}
+		p.Parent().RemoveChild(p.Parent(), p)
+	} else {
+		lines.SetSliced(0, lines.Len()-1)
+	}
+
+	for _, attr := range attrs {
+		target.SetAttribute(attr.Name, attr.Value)
+	}
+}
+
+// attributeLine parses a line that consists of nothing but attributes.
+func attributeLine(line []byte) (parser.Attributes, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return nil, false
+	}
+	r := text.NewReader(line)
+	attrs, ok := parser.ParseAttributes(r)
+	if !ok {
+		return nil, false
+	}
+	r.SkipSpaces()
+	return attrs, r.Peek() == text.EOF
+}
@@ -2,48 +2,341 @@ package content
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"sprout/internal/model"
+
 	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
 )
 
 func ParsePage(sourcePath string, raw []byte) (*model.FrontMatter, []byte, error) {
+	fm, body, _, err := parsePage(raw)
+	return fm, body, err
+}
+
+// parsePage is ParsePage that also returns the offset of the body within
+// raw, so that errors in the body can be reported by line.
+func parsePage(raw []byte) (*model.FrontMatter, []byte, int, error) {
 	fm := &model.FrontMatter{
-		Layout: "page",
+		Params: make(map[string]any),
+	}
+
+	block, err := splitFrontMatter(raw)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if block == nil {
+		return fm, raw, 0, nil
+	}
+
+	values, err := block.decode()
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
-	content := raw
-	delimiter := []byte("+++")
+	if err := applyFrontMatter(fm, values); err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to parse front matter: %w", err)
+	}
+
+	body := bytes.TrimLeftFunc(block.body, unicode.IsSpace)
+	offset := len(raw) - len(body)
+	return fm, bytes.TrimRightFunc(body, unicode.IsSpace), offset, nil
+}
+
+type frontMatterBlock struct {
+	format string
+	data   []byte
+	// offset of data within the source file, used to report error
+	// positions relative to the file rather than the block
+	offset int
+	raw    []byte
+	body   []byte
+}
 
-	if !bytes.HasPrefix(raw, delimiter) {
-		return fm, raw, nil
+// splitFrontMatter detects the front matter format from its opening
+// delimiter: +++ for TOML, --- for YAML and { for JSON. It returns nil if
+// the file has no front matter.
+//
+// A JSON object's opening brace is followed by whitespace, a quoted key or
+// the closing brace, which tells it apart from content that starts with a
+// shortcode such as {{< note >}}.
+func splitFrontMatter(raw []byte) (*frontMatterBlock, error) {
+	switch {
+	case bytes.HasPrefix(raw, []byte("+++")):
+		return splitDelimited(raw, "toml", "+++")
+	case bytes.HasPrefix(raw, []byte("---")):
+		return splitDelimited(raw, "yaml", "---")
+	case len(raw) > 1 && raw[0] == '{' && strings.IndexByte(" \t\r\n\"}", raw[1]) != -1:
+		return splitJSON(raw)
 	}
+	return nil, nil
+}
 
-	endIdx := bytes.Index(raw[3:], delimiter)
+func splitDelimited(raw []byte, format, delimiter string) (*frontMatterBlock, error) {
+	endIdx := bytes.Index(raw[3:], []byte("\n"+delimiter))
 	if endIdx == -1 {
-		return nil, nil, fmt.Errorf("unclosed front matter delimiter: expected closing +++")
+		return nil, fmt.Errorf("unclosed front matter delimiter: expected closing %s", delimiter)
 	}
+	endIdx++
 
-	if endIdx+6 >= len(raw) {
-		return nil, nil, fmt.Errorf("invalid front matter: content too short")
+	if endIdx+6 > len(raw) {
+		return nil, fmt.Errorf("invalid front matter: content too short")
 	}
 
-	frontMatterBytes := raw[3 : endIdx+3]
-	if len(frontMatterBytes) == 0 {
-		return nil, nil, fmt.Errorf("empty front matter block")
+	data := raw[3 : endIdx+3]
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("empty front matter block")
 	}
 
-	content = raw[endIdx+6:]
+	return &frontMatterBlock{
+		format: format,
+		data:   data,
+		offset: 3,
+		raw:    raw,
+		body:   raw[endIdx+6:],
+	}, nil
+}
 
-	if err := toml.Unmarshal(frontMatterBytes, fm); err != nil {
-		return nil, nil, fmt.Errorf("failed to parse front matter: %w", err)
+func splitJSON(raw []byte) (*frontMatterBlock, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	var values map[string]any
+	if err := decoder.Decode(&values); err != nil {
+		block := &frontMatterBlock{format: "json", data: raw, raw: raw}
+		return nil, block.syntaxError(err)
 	}
 
-	content = bytes.TrimSpace(content)
+	end := int(decoder.InputOffset())
+	return &frontMatterBlock{
+		format: "json",
+		data:   raw[:end],
+		raw:    raw,
+		body:   raw[end:],
+	}, nil
+}
 
-	return fm, content, nil
+func (b *frontMatterBlock) decode() (map[string]any, error) {
+	values := make(map[string]any)
+	var err error
+	switch b.format {
+	case "toml":
+		err = toml.Unmarshal(b.data, &values)
+	case "yaml":
+		err = yaml.Unmarshal(b.data, &values)
+	case "json":
+		err = json.Unmarshal(b.data, &values)
+	}
+	if err != nil {
+		return nil, b.syntaxError(err)
+	}
+	return values, nil
+}
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// syntaxError rewrites a parser error so that its position refers to the
+// content file. TOML and JSON report a line and column; YAML only reports
+// a line.
+func (b *frontMatterBlock) syntaxError(err error) error {
+	var decodeErr *toml.DecodeError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &decodeErr):
+		row, col := decodeErr.Position()
+		line, column := b.position(row, col)
+		return fmt.Errorf("invalid %s front matter at line %d, column %d: %s", b.format, line, column, strings.TrimPrefix(decodeErr.Error(), "toml: "))
+	case errors.As(err, &syntaxErr):
+		line, column := lineColumn(b.raw, int(syntaxErr.Offset))
+		return fmt.Errorf("invalid %s front matter at line %d, column %d: %s", b.format, line, column, syntaxErr.Error())
+	case errors.As(err, &typeErr):
+		line, column := lineColumn(b.raw, int(typeErr.Offset))
+		return fmt.Errorf("invalid %s front matter at line %d, column %d: %s", b.format, line, column, typeErr.Error())
+	case b.format == "yaml":
+		startLine, _ := lineColumn(b.raw, b.offset)
+		msg := strings.TrimPrefix(err.Error(), "yaml: ")
+		msg = yamlLinePattern.ReplaceAllStringFunc(msg, func(match string) string {
+			row, _ := strconv.Atoi(strings.TrimPrefix(match, "line "))
+			return fmt.Sprintf("line %d", startLine+row-1)
+		})
+		return fmt.Errorf("invalid yaml front matter: %s", msg)
+	}
+
+	return fmt.Errorf("failed to parse front matter: %w", err)
+}
+
+// position converts a 1-based row and column within the front matter block
+// into a line and column within the source file.
+func (b *frontMatterBlock) position(row, col int) (int, int) {
+	startLine, startColumn := lineColumn(b.raw, b.offset)
+	if row == 1 {
+		return startLine, startColumn + col - 1
+	}
+	return startLine + row - 1, col
+}
+
+func lineColumn(raw []byte, offset int) (int, int) {
+	if offset > len(raw) {
+		offset = len(raw)
+	}
+	before := raw[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// applyFrontMatter copies the fields Sprout understands into fm and keeps
+// every other key in fm.Params. Known keys are matched case-insensitively.
+func applyFrontMatter(fm *model.FrontMatter, values map[string]any) error {
+	for key, value := range values {
+		var err error
+		switch strings.ToLower(key) {
+		case "title":
+			fm.Title, err = stringValue(key, value)
+		case "slug":
+			fm.Slug, err = stringValue(key, value)
+		case "layout":
+			fm.Layout, err = stringValue(key, value)
+		case "date":
+			fm.Date, err = timeValue(key, value)
+		case "publishdate":
+			fm.PublishDate, err = timeValue(key, value)
+		case "expirydate":
+			fm.ExpiryDate, err = timeValue(key, value)
+		case "draft":
+			fm.Draft, err = boolValue(key, value)
+		case "weight":
+			fm.Weight, err = intValue(key, value)
+		case "paginate":
+			fm.Paginate, err = intValue(key, value)
+		case "markup":
+			fm.Markup, err = markupValue(key, value)
+		default:
+			fm.Params[key] = normalizeValue(value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stringValue(key string, value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string, got %T", key, value)
+	}
+	return s, nil
+}
+
+func boolValue(key string, value any) (bool, error) {
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean, got %T", key, value)
+	}
+	return b, nil
+}
+
+func intValue(key string, value any) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("%s must be an integer, got %v", key, value)
+}
+
+// markupValue reads a table of Markdown settings, such as
+// markup = {footnotes = true}.
+func markupValue(key string, value any) (map[string]bool, error) {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a table, got %T", key, value)
+	}
+	values := make(map[string]bool, len(table))
+	for name, v := range table {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a boolean, got %T", key, name, v)
+		}
+		values[name] = b
+	}
+	if _, err := (model.MarkdownConfig{}).Override(values); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return values, nil
+}
+
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func timeValue(key string, value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case toml.LocalDate:
+		return v.AsTime(time.Local), nil
+	case toml.LocalDateTime:
+		return v.AsTime(time.Local), nil
+	case string:
+		t, err := ParseDate(v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", key, err)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be a date, got %T", key, value)
+}
+
+// ParseDate parses the date formats accepted in front matter: RFC 3339, or a
+// date with an optional time, which is taken to be local time.
+func ParseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a date", s)
+}
+
+// normalizeValue converts TOML's local date types to time.Time so that
+// templates can format every date param the same way.
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case toml.LocalDate:
+		return v.AsTime(time.Local)
+	case toml.LocalDateTime:
+		return v.AsTime(time.Local)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeValue(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeValue(item)
+		}
+	}
+	return value
 }
 
 func DeriveSlug(filepath string) string {
@@ -66,3 +359,22 @@ func DeriveSlug(filepath string) string {
 
 	return slug
 }
+
+// Slugify turns arbitrary text such as a tag name into a URL segment:
+// lowercase letters and digits separated by single hyphens.
+func Slugify(s string) string {
+	var buf strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingHyphen && buf.Len() > 0 {
+				buf.WriteByte('-')
+			}
+			pendingHyphen = false
+			buf.WriteRune(r)
+			continue
+		}
+		pendingHyphen = true
+	}
+	return buf.String()
+}
@@ -0,0 +1,126 @@
+package content
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"sprout/internal/model"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+)
+
+// headingIDs generates heading IDs with Slugify, so that "Über uns" becomes
+// über-uns rather than losing its non-ASCII letters. Repeated headings get
+// -1, -2, ... suffixes in document order, which keeps IDs stable as long as
+// the headings before them don't change. text converts the heading text
+// first, so that shortcode placeholders don't end up in IDs.
+type headingIDs struct {
+	used map[string]bool
+	text func(string) string
+}
+
+func newHeadingIDs(text func(string) string) parser.IDs {
+	return &headingIDs{used: make(map[string]bool), text: text}
+}
+
+func (ids *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	id := Slugify(ids.text(string(value)))
+	if id == "" {
+		id = "heading"
+		if kind != ast.KindHeading {
+			id = "id"
+		}
+	}
+
+	unique := id
+	for i := 1; ids.used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", id, i)
+	}
+	ids.used[unique] = true
+	return []byte(unique)
+}
+
+func (ids *headingIDs) Put(value []byte) {
+	ids.used[string(value)] = true
+}
+
+// collectHeadings lists the headings of a document whose level is between
+// start and end inclusive, including those inside the shortcodes it uses.
+func collectHeadings(doc ast.Node, source []byte, start, end int, shortcodes *shortcodeProcessor) []model.Heading {
+	var headings []model.Heading
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if t, ok := n.(*ast.Text); ok {
+			headings = append(headings, shortcodes.headingsIn(t.Segment.Value(source))...)
+		}
+		heading, ok := n.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		if heading.Level >= start && heading.Level <= end {
+			id, _ := heading.AttributeString("id")
+			idBytes, _ := id.([]byte)
+			headings = append(headings, model.Heading{
+				Level: heading.Level,
+				ID:    string(idBytes),
+				Title: strings.TrimSpace(shortcodes.plain(nodeText(heading, source))),
+			})
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return headings
+}
+
+func nodeText(n ast.Node, source []byte) string {
+	var buf strings.Builder
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		switch c := child.(type) {
+		case *ast.Text:
+			buf.Write(c.Segment.Value(source))
+			if c.SoftLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.WriteString(stringText(c))
+		default:
+			buf.WriteString(nodeText(child, source))
+		}
+	}
+	return buf.String()
+}
+
+// tableOfContents renders headings as nested lists. Levels may be skipped;
+// a heading deeper than the one before it always starts a new nested list.
+func tableOfContents(headings []model.Heading) string {
+	if len(headings) == 0 {
+		return ""
+	}
+
+	var buf strings.Builder
+	buf.WriteString("<nav id=\"TableOfContents\">\n<ul>\n")
+	levels := []int{headings[0].Level}
+	for i, heading := range headings {
+		if i > 0 {
+			for len(levels) > 1 && heading.Level < levels[len(levels)-1] {
+				buf.WriteString("</li>\n</ul>\n")
+				levels = levels[:len(levels)-1]
+			}
+			if heading.Level > levels[len(levels)-1] {
+				buf.WriteString("\n<ul>\n")
+				levels = append(levels, heading.Level)
+			} else {
+				buf.WriteString("</li>\n")
+			}
+		}
+		fmt.Fprintf(&buf, "<li><a href=\"#%s\">%s</a>", html.EscapeString(heading.ID), html.EscapeString(heading.Title))
+	}
+	for range levels {
+		buf.WriteString("</li>\n</ul>\n")
+	}
+	buf.WriteString("</nav>")
+	return buf.String()
+}
@@ -0,0 +1,157 @@
+package content
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"sprout/internal/highlight"
+	"sprout/internal/model"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// fenceInfo splits a fenced code block's info string, such as
+// "go {linenos=true, hl_lines=[3,5]}", into the language and attributes.
+// Other text after the language, such as a file name, is ignored.
+func fenceInfo(n *ast.FencedCodeBlock, source []byte) (string, map[string]any) {
+	if n.Info == nil {
+		return "", nil
+	}
+	info := n.Info.Segment.Value(source)
+
+	i := bytes.IndexAny(info, " \t{")
+	if i == -1 {
+		return string(info), nil
+	}
+	lang := string(info[:i])
+
+	rest := bytes.TrimSpace(info[i:])
+	if len(rest) == 0 || rest[0] != '{' {
+		return lang, nil
+	}
+	parsed, ok := parser.ParseAttributes(text.NewReader(rest))
+	if !ok {
+		return lang, nil
+	}
+
+	attrs := make(map[string]any, len(parsed))
+	for _, attr := range parsed {
+		attrs[string(attr.Name)] = attributeValue(attr.Value)
+	}
+	return lang, attrs
+}
+
+// attributeValue converts goldmark's attribute values, which hold strings
+// as []byte, to plain Go values.
+func attributeValue(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case []any:
+		values := make([]any, len(v))
+		for i, item := range v {
+			values[i] = attributeValue(item)
+		}
+		return values
+	}
+	return value
+}
+
+// highlightOptions combines the site's highlight settings with a code
+// block's linenos, linenostart and hl_lines attributes.
+func highlightOptions(cfg model.HighlightConfig, attrs map[string]any) (highlight.Options, error) {
+	opts := highlight.Options{
+		Style:   cfg.Style,
+		Classes: cfg.Classes,
+	}
+	if cfg.LineNumbers {
+		opts.LineNumbers = "table"
+	}
+
+	if value, ok := attrs["linenos"]; ok {
+		switch v := value.(type) {
+		case bool:
+			opts.LineNumbers = ""
+			if v {
+				opts.LineNumbers = "table"
+			}
+		case string:
+			switch v {
+			case "table", "inline":
+				opts.LineNumbers = v
+			case "false":
+				opts.LineNumbers = ""
+			case "true":
+				opts.LineNumbers = "table"
+			default:
+				return opts, fmt.Errorf("invalid linenos %q: expected true, false, table or inline", v)
+			}
+		default:
+			return opts, fmt.Errorf("invalid linenos %v: expected true, false, table or inline", value)
+		}
+	}
+
+	if value, ok := attrs["linenostart"]; ok {
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || n < 0 {
+			return opts, fmt.Errorf("invalid linenostart %v: expected a line number", value)
+		}
+		opts.LineNumberStart = int(n)
+	}
+
+	if value, ok := attrs["hl_lines"]; ok {
+		ranges, err := lineRanges(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid hl_lines: %w", err)
+		}
+		opts.HighlightLines = ranges
+	}
+
+	return opts, nil
+}
+
+// lineRanges reads hl_lines, which is a line number, a string of numbers
+// and ranges such as "3-5 8", or a list of either.
+func lineRanges(value any) ([][2]int, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) || v < 1 {
+			return nil, fmt.Errorf("%v is not a line number", v)
+		}
+		return [][2]int{{int(v), int(v)}}, nil
+	case string:
+		var ranges [][2]int
+		for _, field := range strings.Fields(v) {
+			from, to, isRange := strings.Cut(field, "-")
+			start, err := strconv.Atoi(from)
+			if err != nil || start < 1 {
+				return nil, fmt.Errorf("%q is not a line number or range", field)
+			}
+			end := start
+			if isRange {
+				end, err = strconv.Atoi(to)
+				if err != nil || end < start {
+					return nil, fmt.Errorf("%q is not a line number or range", field)
+				}
+			}
+			ranges = append(ranges, [2]int{start, end})
+		}
+		return ranges, nil
+	case []any:
+		var ranges [][2]int
+		for _, item := range v {
+			r, err := lineRanges(item)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, r...)
+		}
+		return ranges, nil
+	}
+	return nil, fmt.Errorf("%v is not a line number or range", value)
+}
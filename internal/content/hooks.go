@@ -0,0 +1,251 @@
+package content
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"sprout/internal/highlight"
+	"sprout/internal/model"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// hookPriority puts the hook renderer ahead of goldmark's HTML renderer,
+// which has priority 1000.
+const hookPriority = 100
+
+// hookRenderer renders links, images, headings and fenced code blocks with
+// render hook templates, falling back to goldmark's HTML renderer for
+// elements without one. Code blocks without a hook are highlighted if
+// highlighting is enabled.
+type hookRenderer struct {
+	hook      func(name string, ctx *model.HookContext) (template.HTML, bool, error)
+	highlight model.HighlightConfig
+	html      renderer.NodeRenderer
+	defaults  map[ast.NodeKind]renderer.NodeRendererFunc
+	// hooked holds the nodes rendered by a hook, whose closing tags the
+	// default renderer must not write.
+	hooked map[ast.Node]bool
+	// renderer renders the children of a node, such as a link's text.
+	renderer renderer.Renderer
+}
+
+func newHookRenderer(hook func(name string, ctx *model.HookContext) (template.HTML, bool, error), highlight model.HighlightConfig) *hookRenderer {
+	return &hookRenderer{
+		hook:      hook,
+		highlight: highlight,
+		html:      html.NewRenderer(),
+		defaults:  make(map[ast.NodeKind]renderer.NodeRendererFunc),
+		hooked:    make(map[ast.Node]bool),
+	}
+}
+
+// SetOption passes renderer options such as html.WithUnsafe on to the
+// default renderer.
+func (r *hookRenderer) SetOption(name renderer.OptionName, value any) {
+	if setter, ok := r.html.(renderer.SetOptioner); ok {
+		setter.SetOption(name, value)
+	}
+}
+
+func (r *hookRenderer) Register(kind ast.NodeKind, f renderer.NodeRendererFunc) {
+	r.defaults[kind] = f
+}
+
+func (r *hookRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	r.html.RegisterFuncs(r)
+	reg.Register(ast.KindLink, r.renderLink)
+	reg.Register(ast.KindAutoLink, r.renderAutoLink)
+	reg.Register(ast.KindImage, r.renderImage)
+	reg.Register(ast.KindHeading, r.renderHeading)
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+// call runs the first hook of names that has a template. It returns false if
+// none has one.
+func (r *hookRenderer) call(w util.BufWriter, ctx *model.HookContext, names ...string) (bool, error) {
+	if r.hook == nil {
+		return false, nil
+	}
+	for _, name := range names {
+		output, ok, err := r.hook(name, ctx)
+		if err != nil {
+			return false, fmt.Errorf("%s render hook: %w", name, err)
+		}
+		if ok {
+			_, _ = w.WriteString(strings.TrimSpace(string(output)))
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *hookRenderer) renderChildren(source []byte, n ast.Node) (template.HTML, error) {
+	var buf bytes.Buffer
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if err := r.renderer.Render(&buf, source, child); err != nil {
+			return "", err
+		}
+	}
+	return template.HTML(buf.String()), nil
+}
+
+func (r *hookRenderer) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return r.exit(w, source, node)
+	}
+	n := node.(*ast.Link)
+	text, err := r.renderChildren(source, n)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	ok, err := r.call(w, &model.HookContext{
+		Destination: string(n.Destination),
+		Title:       string(n.Title),
+		Text:        text,
+		PlainText:   nodeText(n, source),
+	}, "link")
+	if err != nil || !ok {
+		return r.fallback(w, source, node, err)
+	}
+	r.hooked[node] = true
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *hookRenderer) renderAutoLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.AutoLink)
+	destination := string(n.URL(source))
+	if n.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(strings.ToLower(destination), "mailto:") {
+		destination = "mailto:" + destination
+	}
+	label := string(n.Label(source))
+	ok, err := r.call(w, &model.HookContext{
+		Destination: destination,
+		Text:        template.HTML(template.HTMLEscapeString(label)),
+		PlainText:   label,
+	}, "link")
+	if err != nil || !ok {
+		return r.fallback(w, source, node, err)
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *hookRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Image)
+	alt := nodeText(n, source)
+	ok, err := r.call(w, &model.HookContext{
+		Destination: string(n.Destination),
+		Title:       string(n.Title),
+		Text:        template.HTML(template.HTMLEscapeString(alt)),
+		PlainText:   alt,
+	}, "image")
+	if err != nil || !ok {
+		return r.fallback(w, source, node, err)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *hookRenderer) renderHeading(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return r.exit(w, source, node)
+	}
+	n := node.(*ast.Heading)
+	text, err := r.renderChildren(source, n)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	var id string
+	if value, ok := n.AttributeString("id"); ok {
+		if b, ok := value.([]byte); ok {
+			id = string(b)
+		}
+	}
+	ok, err := r.call(w, &model.HookContext{
+		Level:     n.Level,
+		ID:        id,
+		Text:      text,
+		PlainText: nodeText(n, source),
+	}, "heading")
+	if err != nil || !ok {
+		return r.fallback(w, source, node, err)
+	}
+	_ = w.WriteByte('\n')
+	r.hooked[node] = true
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *hookRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return r.exit(w, source, node)
+	}
+	n := node.(*ast.FencedCodeBlock)
+	var code bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		code.Write(segment.Value(source))
+	}
+	lang, attrs := fenceInfo(n, source)
+
+	names := []string{"codeblock"}
+	if lang != "" {
+		names = []string{"codeblock-" + lang, "codeblock"}
+	}
+	ok, err := r.call(w, &model.HookContext{
+		Lang:       lang,
+		Code:       code.String(),
+		Attributes: attrs,
+	}, names...)
+	if err == nil && !ok && r.highlight.Enabled && lang != "" {
+		ok, err = r.highlightCode(w, code.String(), lang, attrs)
+	}
+	if err != nil || !ok {
+		return r.fallback(w, source, node, err)
+	}
+	_ = w.WriteByte('\n')
+	r.hooked[node] = true
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *hookRenderer) highlightCode(w util.BufWriter, code, lang string, attrs map[string]any) (bool, error) {
+	opts, err := highlightOptions(r.highlight, attrs)
+	if err != nil {
+		return false, fmt.Errorf("%s code block: %w", lang, err)
+	}
+	var buf bytes.Buffer
+	ok, err := highlight.Highlight(&buf, code, lang, opts)
+	if err != nil || !ok {
+		return false, err
+	}
+	_, _ = w.Write(bytes.TrimSpace(buf.Bytes()))
+	return true, nil
+}
+
+// exit closes a node that was opened by the default renderer.
+func (r *hookRenderer) exit(w util.BufWriter, source []byte, node ast.Node) (ast.WalkStatus, error) {
+	if r.hooked[node] {
+		delete(r.hooked, node)
+		return ast.WalkContinue, nil
+	}
+	return r.defaults[node.Kind()](w, source, node, false)
+}
+
+// fallback renders a node with goldmark's default renderer after a hook
+// failed or wasn't found.
+func (r *hookRenderer) fallback(w util.BufWriter, source []byte, node ast.Node, err error) (ast.WalkStatus, error) {
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	return r.defaults[node.Kind()](w, source, node, true)
+}
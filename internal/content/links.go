@@ -0,0 +1,53 @@
+package content
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// resolveLinks rewrites links to other content files, such as
+// [text](../other.md#install), to the URLs that resolve returns for them.
+// resolve gets the file's path as written, without the query or fragment.
+func resolveLinks(doc ast.Node, resolve func(target string) (string, error)) error {
+	if resolve == nil {
+		return nil
+	}
+	return ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		link, ok := n.(*ast.Link)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		destination := string(link.Destination)
+		target, suffix, ok := contentLink(destination)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		permalink, err := resolve(target)
+		if err != nil {
+			return ast.WalkStop, fmt.Errorf("broken link to %q: %w", destination, err)
+		}
+		link.Destination = []byte(permalink + suffix)
+		return ast.WalkContinue, nil
+	})
+}
+
+// contentLink reports whether a link destination points at a Markdown file
+// rather than a URL, and splits it into the file's path and the query and
+// fragment that follow it.
+func contentLink(destination string) (string, string, bool) {
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "", "", false
+	}
+	if !strings.HasSuffix(strings.ToLower(u.Path), ".md") {
+		return "", "", false
+	}
+	var suffix string
+	if i := strings.IndexAny(destination, "?#"); i != -1 {
+		suffix = destination[i:]
+	}
+	return u.Path, suffix, true
+}
@@ -2,38 +2,147 @@ package content
 
 import (
 	"bytes"
+	"html/template"
 	"strings"
 
 	"sprout/internal/model"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
-func newMarkdownRenderer(unsafeHTML bool) goldmark.Markdown {
-	var opts []goldmark.Option
-	
-	opts = append(opts, goldmark.WithExtensions(extension.GFM))
-	
-	if unsafeHTML {
-		opts = append(opts, goldmark.WithRendererOptions(html.WithUnsafe()))
+// Options controls how Markdown is rendered.
+type Options struct {
+	UnsafeHTML bool
+	Markdown   model.MarkdownConfig
+	// TOCStartLevel and TOCEndLevel limit the headings listed in the table
+	// of contents and in Result.Headings.
+	TOCStartLevel int
+	TOCEndLevel   int
+	// SummaryLength is the number of words in summaries of pages without
+	// a <!--more--> divider.
+	SummaryLength int
+	Highlight     model.HighlightConfig
+	// RenderShortcode executes the template for a shortcode call. Content
+	// with shortcodes is an error when it is nil.
+	RenderShortcode func(sc *model.Shortcode) (template.HTML, error)
+	// RenderHook executes the render hook template for a Markdown element,
+	// such as "link" or "codeblock-mermaid", and reports false if there is
+	// none. Without it, every element is rendered by goldmark.
+	RenderHook func(name string, ctx *model.HookContext) (template.HTML, bool, error)
+	// ResolveLink returns the URL of the content file a link points to, such
+	// as "../other.md". Without it, such links are left as they are.
+	ResolveLink func(target string) (string, error)
+}
+
+// Result is a rendered content file.
+type Result struct {
+	FrontMatter     *model.FrontMatter
+	HTML            []byte
+	DerivedTitle    string
+	TableOfContents template.HTML
+	Headings        []model.Heading
+	Summary         template.HTML
+	Truncated       bool
+	WordCount       int
+	ReadingTime     int
+	// Shortcodes lists the names of the shortcodes the content uses.
+	Shortcodes []string
+}
+
+func newMarkdownRenderer(opts Options) goldmark.Markdown {
+	var gmOpts []goldmark.Option
+
+	gmOpts = append(gmOpts, goldmark.WithExtensions(extension.GFM))
+	gmOpts = append(gmOpts, goldmark.WithParserOptions(parser.WithAutoHeadingID()))
+
+	if opts.UnsafeHTML {
+		gmOpts = append(gmOpts, goldmark.WithRendererOptions(html.WithUnsafe()))
+	}
+
+	md := opts.Markdown
+	if md.Footnotes {
+		gmOpts = append(gmOpts, goldmark.WithExtensions(extension.Footnote))
+	}
+	if md.DefinitionLists {
+		gmOpts = append(gmOpts, goldmark.WithExtensions(extension.DefinitionList))
+	}
+	if md.Typographer {
+		gmOpts = append(gmOpts, goldmark.WithExtensions(extension.Typographer))
+	}
+	if md.Attributes {
+		gmOpts = append(gmOpts, goldmark.WithParserOptions(
+			parser.WithAttribute(),
+			parser.WithParagraphTransformers(util.Prioritized(blockAttributes{}, 100)),
+		))
+	}
+	if md.HardWraps {
+		gmOpts = append(gmOpts, goldmark.WithRendererOptions(html.WithHardWraps()))
+	}
+	if md.XHTML {
+		gmOpts = append(gmOpts, goldmark.WithRendererOptions(html.WithXHTML()))
+	}
+
+	if opts.RenderHook == nil && !opts.Highlight.Enabled {
+		return goldmark.New(gmOpts...)
 	}
 
-	return goldmark.New(opts...)
+	hooks := newHookRenderer(opts.RenderHook, opts.Highlight)
+	gmOpts = append(gmOpts, goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(hooks, hookPriority)),
+	))
+	markdown := goldmark.New(gmOpts...)
+	hooks.renderer = markdown.Renderer()
+	return markdown
 }
 
 func RenderMarkdown(markdown []byte, unsafeHTML bool) ([]byte, string, error) {
-	md := newMarkdownRenderer(unsafeHTML)
-	var buf bytes.Buffer
-	if err := md.Convert(markdown, &buf); err != nil {
+	result, err := render(markdown, Options{UnsafeHTML: unsafeHTML}, nil)
+	if err != nil {
 		return nil, "", err
 	}
+	return result.HTML, result.DerivedTitle, nil
+}
+
+// render converts markdown to HTML. shortcodes, if the Markdown has any,
+// turns their placeholders back into text for headings and the title.
+func render(markdown []byte, opts Options, shortcodes *shortcodeProcessor) (*Result, error) {
+	md := newMarkdownRenderer(opts)
+	ctx := parser.NewContext(parser.WithIDs(shortcodes.headingIDs()))
+	doc := md.Parser().Parse(text.NewReader(markdown), parser.WithContext(ctx))
+	if err := resolveLinks(doc, opts.ResolveLink); err != nil {
+		return nil, err
+	}
+
+	// Summarizing removes the <!--more--> divider, so it comes first.
+	summary, truncated, err := summarize(md.Renderer(), markdown, doc, opts.SummaryLength)
+	if err != nil {
+		return nil, err
+	}
+	wordCount := len(plainWords(doc, markdown, true))
+
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, markdown, doc); err != nil {
+		return nil, err
+	}
 
-	html := buf.Bytes()
-	title := extractFirstH1(markdown)
+	headings := collectHeadings(doc, markdown, opts.TOCStartLevel, opts.TOCEndLevel, shortcodes)
 
-	return html, title, nil
+	return &Result{
+		HTML:            buf.Bytes(),
+		DerivedTitle:    shortcodes.plain(extractFirstH1(markdown)),
+		TableOfContents: template.HTML(tableOfContents(headings)),
+		Headings:        headings,
+		Summary:         summary,
+		Truncated:       truncated,
+		WordCount:       wordCount,
+		ReadingTime:     readingTime(wordCount),
+	}, nil
 }
 
 func extractFirstH1(markdown []byte) string {
@@ -48,24 +157,37 @@ func extractFirstH1(markdown []byte) string {
 	return ""
 }
 
-func ParseAndRender(sourcePath string, raw []byte, unsafeHTML bool) (*model.FrontMatter, []byte, string, error) {
-	fm, markdown, err := ParsePage(sourcePath, raw)
+func ParseAndRender(sourcePath string, raw []byte, opts Options) (*Result, error) {
+	fm, markdown, offset, err := parsePage(raw)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, err
+	}
+
+	var shortcodes *shortcodeProcessor
+	var names []string
+	if hasShortcodes(markdown) {
+		markdown, shortcodes, names, err = expandShortcodes(raw, markdown, offset, opts)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	html, derivedTitle, err := RenderMarkdown(markdown, unsafeHTML)
+	result, err := render(markdown, opts, shortcodes)
 	if err != nil {
-		return nil, nil, "", err
+		return nil, err
 	}
+	result.HTML = shortcodes.resolve(result.HTML)
+	result.Summary = template.HTML(shortcodes.resolve([]byte(result.Summary)))
+	result.Shortcodes = names
 
 	if fm.Title == "" {
-		fm.Title = derivedTitle
+		fm.Title = result.DerivedTitle
 	}
 
 	if fm.Slug == "" {
 		fm.Slug = DeriveSlug(sourcePath)
 	}
 
-	return fm, html, derivedTitle, nil
+	result.FrontMatter = fm
+	return result, nil
 }
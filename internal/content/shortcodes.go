@@ -0,0 +1,447 @@
+package content
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sprout/internal/model"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// shortcodeTag is an opening, closing or commented-out shortcode tag found
+// in a page's Markdown. start and end are byte offsets in the Markdown.
+type shortcodeTag struct {
+	name        string
+	closing     bool
+	selfClosing bool
+	markdown    bool
+	comment     bool
+	literal     string
+	params      map[string]string
+	positional  []string
+	start, end  int
+}
+
+// Shortcode output is swapped for a placeholder before the Markdown is
+// rendered, so goldmark neither escapes it nor drops it as raw HTML, and
+// put back into the rendered HTML afterwards.
+const placeholderFormat = "SPROUTSHORTCODE%dEND"
+
+var (
+	placeholderPattern          = regexp.MustCompile(`SPROUTSHORTCODE(\d+)END`)
+	paragraphPlaceholderPattern = regexp.MustCompile(`<p>SPROUTSHORTCODE(\d+)END</p>`)
+	tagPattern                  = regexp.MustCompile(`<[^>]*>`)
+)
+
+type shortcodeProcessor struct {
+	source []byte
+	// offset of the Markdown within the source file, for line numbers
+	offset      int
+	md          goldmark.Markdown
+	render      func(sc *model.Shortcode) (template.HTML, error)
+	resolveLink func(target string) (string, error)
+	outputs     []template.HTML
+	ordinals    map[string]int
+	// ids is shared by the page and the Markdown inside its shortcodes, so
+	// that heading IDs are unique across both. Shortcodes are expanded
+	// first, so their headings get the unsuffixed IDs.
+	ids parser.IDs
+	// headings lists, for each output, the headings of its .Inner, which the
+	// table of contents includes where the placeholder is.
+	headings         [][]model.Heading
+	tocStart, tocEnd int
+}
+
+func hasShortcodes(markdown []byte) bool {
+	return bytes.Contains(markdown, []byte("{{<")) || bytes.Contains(markdown, []byte("{{%"))
+}
+
+// expandShortcodes replaces every shortcode call in markdown with a
+// placeholder. It returns the new Markdown, the processor that resolves the
+// placeholders and the names of the shortcodes used.
+func expandShortcodes(source, markdown []byte, offset int, opts Options) ([]byte, *shortcodeProcessor, []string, error) {
+	p := &shortcodeProcessor{
+		source:      source,
+		offset:      offset,
+		md:          newMarkdownRenderer(opts),
+		render:      opts.RenderShortcode,
+		resolveLink: opts.ResolveLink,
+		ordinals:    make(map[string]int),
+		tocStart:    opts.TOCStartLevel,
+		tocEnd:      opts.TOCEndLevel,
+	}
+	p.ids = newHeadingIDs(p.plain)
+
+	tags, err := p.scan(markdown)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	out, err := p.expand(markdown, 0, len(markdown), tags)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	names := make([]string, 0, len(p.ordinals))
+	for name := range p.ordinals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return []byte(out), p, names, nil
+}
+
+func (p *shortcodeProcessor) line(pos int) int {
+	line, _ := lineColumn(p.source, p.offset+pos)
+	return line
+}
+
+func (p *shortcodeProcessor) scan(markdown []byte) ([]shortcodeTag, error) {
+	var tags []shortcodeTag
+	pos := 0
+	for {
+		i := bytes.Index(markdown[pos:], []byte("{{"))
+		if i == -1 || pos+i+2 >= len(markdown) {
+			return tags, nil
+		}
+		start := pos + i
+		delim := markdown[start+2]
+		if delim != '<' && delim != '%' {
+			pos = start + 2
+			continue
+		}
+
+		tag, err := lexShortcode(markdown, start, delim)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shortcode at line %d: %w", p.line(start), err)
+		}
+		tags = append(tags, tag)
+		pos = tag.end
+	}
+}
+
+// lexShortcode reads the tag that starts at src[start]. Arguments are
+// separated by spaces and are either all positional or all key=value;
+// values can be bare words, "quoted strings" or `raw strings`.
+func lexShortcode(src []byte, start int, delim byte) (shortcodeTag, error) {
+	closeDelim := ">}}"
+	if delim == '%' {
+		closeDelim = "%}}"
+	}
+	tag := shortcodeTag{markdown: delim == '%', start: start}
+	pos := start + 3
+
+	skipSpace := func() {
+		for pos < len(src) && isSpace(src[pos]) {
+			pos++
+		}
+	}
+	at := func(s string) bool {
+		return bytes.HasPrefix(src[pos:], []byte(s))
+	}
+
+	skipSpace()
+	if at("/*") {
+		end := bytes.Index(src[pos:], []byte("*/"+closeDelim))
+		if end == -1 {
+			return tag, fmt.Errorf("unclosed shortcode comment: expected */%s", closeDelim)
+		}
+		tag.comment = true
+		tag.literal = "{{" + string(delim) + strings.TrimRight(string(src[pos+2:pos+end]), " \t") + " " + closeDelim
+		tag.end = pos + end + 2 + len(closeDelim)
+		return tag, nil
+	}
+
+	if at("/") {
+		tag.closing = true
+		pos++
+		skipSpace()
+	}
+
+	nameStart := pos
+	for pos < len(src) && isNameChar(src[pos]) {
+		pos++
+	}
+	tag.name = string(src[nameStart:pos])
+	if tag.name == "" {
+		return tag, fmt.Errorf("missing shortcode name")
+	}
+
+	for {
+		skipSpace()
+		switch {
+		case pos >= len(src):
+			return tag, fmt.Errorf("unclosed shortcode %q: expected %s", tag.name, closeDelim)
+		case at(closeDelim):
+			tag.end = pos + len(closeDelim)
+			return tag, nil
+		case at("/" + closeDelim):
+			if tag.closing {
+				return tag, fmt.Errorf("closing tag for %q cannot be self-closing", tag.name)
+			}
+			tag.selfClosing = true
+			tag.end = pos + 1 + len(closeDelim)
+			return tag, nil
+		case tag.closing:
+			return tag, fmt.Errorf("closing tag for %q cannot have arguments", tag.name)
+		}
+
+		var key string
+		if src[pos] != '"' && src[pos] != '`' {
+			wordStart := pos
+			for pos < len(src) && !isSpace(src[pos]) && src[pos] != '=' && !at(closeDelim) && !at("/"+closeDelim) {
+				pos++
+			}
+			if pos < len(src) && src[pos] == '=' {
+				key = string(src[wordStart:pos])
+				pos++
+			} else {
+				pos = wordStart
+			}
+		}
+
+		value, n, err := lexValue(src[pos:], closeDelim)
+		if err != nil {
+			return tag, fmt.Errorf("shortcode %q: %w", tag.name, err)
+		}
+		pos += n
+
+		if key == "" {
+			tag.positional = append(tag.positional, value)
+		} else {
+			if tag.params == nil {
+				tag.params = make(map[string]string)
+			}
+			tag.params[key] = value
+		}
+		if tag.params != nil && tag.positional != nil {
+			return tag, fmt.Errorf("shortcode %q: cannot mix named and positional arguments", tag.name)
+		}
+	}
+}
+
+// lexValue reads one argument value and returns it with the number of bytes
+// it took up.
+func lexValue(src []byte, closeDelim string) (string, int, error) {
+	if len(src) == 0 {
+		return "", 0, fmt.Errorf("missing argument value")
+	}
+
+	switch src[0] {
+	case '"':
+		for i := 1; i < len(src); i++ {
+			switch src[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(string(src[:i+1]))
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid quoted argument %s", src[:i+1])
+				}
+				return value, i + 1, nil
+			}
+		}
+		return "", 0, fmt.Errorf("unterminated quoted argument")
+	case '`':
+		end := bytes.IndexByte(src[1:], '`')
+		if end == -1 {
+			return "", 0, fmt.Errorf("unterminated raw argument")
+		}
+		return string(src[1 : end+1]), end + 2, nil
+	}
+
+	n := 0
+	for n < len(src) && !isSpace(src[n]) && !bytes.HasPrefix(src[n:], []byte(closeDelim)) && !bytes.HasPrefix(src[n:], []byte("/"+closeDelim)) {
+		n++
+	}
+	if n == 0 {
+		return "", 0, fmt.Errorf("missing argument value")
+	}
+	return string(src[:n]), n, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '/'
+}
+
+// expand renders the shortcodes in markdown[from:to], whose tags are given,
+// and returns the text with each call replaced by a placeholder.
+func (p *shortcodeProcessor) expand(markdown []byte, from, to int, tags []shortcodeTag) (string, error) {
+	var out strings.Builder
+	pos := from
+	for i := 0; i < len(tags); i++ {
+		tag := tags[i]
+		out.Write(markdown[pos:tag.start])
+		pos = tag.end
+
+		if tag.comment {
+			out.WriteString(tag.literal)
+			continue
+		}
+		if tag.closing {
+			return "", fmt.Errorf("unexpected closing shortcode %q at line %d", tag.name, p.line(tag.start))
+		}
+
+		var inner template.HTML
+		var headings []model.Heading
+		if closer := findClosing(tags, i); closer != -1 {
+			body, err := p.expand(markdown, tag.end, tags[closer].start, tags[i+1:closer])
+			if err != nil {
+				return "", err
+			}
+			inner, headings, err = p.inner(tag, body)
+			if err != nil {
+				return "", err
+			}
+			pos = tags[closer].end
+			i = closer
+		}
+
+		sc := &model.Shortcode{
+			Name:       tag.name,
+			Params:     tag.params,
+			Positional: tag.positional,
+			Inner:      inner,
+			Ordinal:    p.ordinals[tag.name],
+		}
+		p.ordinals[tag.name]++
+		if sc.Params == nil {
+			sc.Params = make(map[string]string)
+		}
+
+		if p.render == nil {
+			return "", fmt.Errorf("shortcode %q at line %d: shortcodes are not supported here", tag.name, p.line(tag.start))
+		}
+		html, err := p.render(sc)
+		if err != nil {
+			return "", fmt.Errorf("shortcode %q at line %d: %w", tag.name, p.line(tag.start), err)
+		}
+
+		if !strings.Contains(string(html), string(inner)) {
+			headings = nil
+		}
+
+		fmt.Fprintf(&out, placeholderFormat, len(p.outputs))
+		p.outputs = append(p.outputs, html)
+		p.headings = append(p.headings, headings)
+	}
+	out.Write(markdown[pos:to])
+	return out.String(), nil
+}
+
+// findClosing returns the index of the tag that closes tags[i], or -1 if it
+// is used on its own.
+func findClosing(tags []shortcodeTag, i int) int {
+	if tags[i].selfClosing {
+		return -1
+	}
+	depth := 0
+	for j := i + 1; j < len(tags); j++ {
+		tag := tags[j]
+		if tag.comment || tag.name != tags[i].name {
+			continue
+		}
+		switch {
+		case tag.closing && depth == 0:
+			return j
+		case tag.closing:
+			depth--
+		case !tag.selfClosing:
+			depth++
+		}
+	}
+	return -1
+}
+
+// inner converts the text between an opening and closing tag, in which
+// nested shortcodes have already been replaced, to the shortcode's .Inner,
+// and returns the headings in it.
+func (p *shortcodeProcessor) inner(tag shortcodeTag, body string) (template.HTML, []model.Heading, error) {
+	if !tag.markdown {
+		return template.HTML(p.resolve([]byte(body))), p.headingsIn([]byte(body)), nil
+	}
+
+	source := []byte(strings.TrimSpace(body))
+	ctx := parser.NewContext(parser.WithIDs(p.ids))
+	doc := p.md.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+	if err := resolveLinks(doc, p.resolveLink); err != nil {
+		return "", nil, fmt.Errorf("shortcode %q at line %d: %w", tag.name, p.line(tag.start), err)
+	}
+	var buf bytes.Buffer
+	if err := p.md.Renderer().Render(&buf, source, doc); err != nil {
+		return "", nil, fmt.Errorf("shortcode %q at line %d: %w", tag.name, p.line(tag.start), err)
+	}
+	headings := collectHeadings(doc, source, p.tocStart, p.tocEnd, p)
+	return template.HTML(bytes.TrimSpace(p.resolve(buf.Bytes()))), headings, nil
+}
+
+// resolve puts shortcode output back in place of its placeholders. A
+// placeholder that goldmark wrapped in a paragraph of its own is replaced
+// with the paragraph, since shortcodes usually output block elements.
+func (p *shortcodeProcessor) resolve(html []byte) []byte {
+	if p == nil {
+		return html
+	}
+	replace := func(pattern *regexp.Regexp, html []byte) []byte {
+		return pattern.ReplaceAllFunc(html, func(match []byte) []byte {
+			n, err := strconv.Atoi(string(pattern.FindSubmatch(match)[1]))
+			if err != nil || n >= len(p.outputs) {
+				return match
+			}
+			return []byte(p.outputs[n])
+		})
+	}
+	return replace(placeholderPattern, replace(paragraphPlaceholderPattern, html))
+}
+
+// plain replaces the placeholders in text taken from the Markdown, such as a
+// heading, with the text of the shortcode output, without its tags.
+func (p *shortcodeProcessor) plain(s string) string {
+	if p == nil {
+		return s
+	}
+	return placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		n, err := strconv.Atoi(placeholderPattern.FindStringSubmatch(match)[1])
+		if err != nil || n >= len(p.outputs) {
+			return match
+		}
+		return html.UnescapeString(tagPattern.ReplaceAllString(string(p.outputs[n]), ""))
+	})
+}
+
+// headingIDs returns the heading IDs for the page's own Markdown.
+func (p *shortcodeProcessor) headingIDs() parser.IDs {
+	if p == nil {
+		return newHeadingIDs(p.plain)
+	}
+	return p.ids
+}
+
+// headingsIn returns the headings of the shortcodes whose placeholders are
+// in text.
+func (p *shortcodeProcessor) headingsIn(text []byte) []model.Heading {
+	if p == nil {
+		return nil
+	}
+	var headings []model.Heading
+	for _, match := range placeholderPattern.FindAllSubmatch(text, -1) {
+		n, err := strconv.Atoi(string(match[1]))
+		if err == nil && n < len(p.headings) {
+			headings = append(headings, p.headings[n]...)
+		}
+	}
+	return headings
+}
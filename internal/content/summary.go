@@ -0,0 +1,118 @@
+package content
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer"
+)
+
+const (
+	summaryDivider = "<!--more-->"
+	wordsPerMinute = 200
+)
+
+// findDivider returns the top-level <!--more--> block, or nil.
+func findDivider(doc ast.Node, source []byte) ast.Node {
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		block, ok := n.(*ast.HTMLBlock)
+		if !ok {
+			continue
+		}
+		var buf bytes.Buffer
+		lines := block.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			segment := lines.At(i)
+			buf.Write(segment.Value(source))
+		}
+		if block.HasClosure() {
+			buf.Write(block.ClosureLine.Value(source))
+		}
+		if strings.TrimSpace(buf.String()) == summaryDivider {
+			return n
+		}
+	}
+	return nil
+}
+
+// renderBefore renders the top-level nodes that come before the divider.
+func renderBefore(r renderer.Renderer, source []byte, doc, divider ast.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	for n := doc.FirstChild(); n != nil && n != divider; n = n.NextSibling() {
+		if err := r.Render(&buf, source, n); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// plainWords returns the words of a document's text. Code blocks, raw HTML,
+// footnotes and shortcodes are left out; headings are left out too unless
+// includeHeadings is set, so that a summary doesn't repeat the page title.
+func plainWords(doc ast.Node, source []byte, includeHeadings bool) []string {
+	var buf strings.Builder
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.RawHTML, *east.FootnoteList:
+			return ast.WalkSkipChildren, nil
+		case *ast.Heading:
+			if !includeHeadings {
+				return ast.WalkSkipChildren, nil
+			}
+		case *ast.Text:
+			buf.Write(node.Segment.Value(source))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.WriteString(stringText(node))
+		}
+		if n.Type() == ast.TypeBlock {
+			buf.WriteByte(' ')
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Fields(placeholderPattern.ReplaceAllString(buf.String(), " "))
+}
+
+// summarize returns the page summary and whether it is shorter than the
+// content. With a <!--more--> divider the summary is the rendered content
+// before it; otherwise it is the first length words of plain text.
+func summarize(r renderer.Renderer, source []byte, doc ast.Node, length int) (template.HTML, bool, error) {
+	if divider := findDivider(doc, source); divider != nil {
+		summary, err := renderBefore(r, source, doc, divider)
+		if err != nil {
+			return "", false, err
+		}
+		truncated := divider.NextSibling() != nil
+		doc.RemoveChild(doc, divider)
+		return template.HTML(bytes.TrimSpace(summary)), truncated, nil
+	}
+
+	words := plainWords(doc, source, false)
+	if len(words) <= length {
+		return template.HTML(html.EscapeString(strings.Join(words, " "))), false, nil
+	}
+	return template.HTML(html.EscapeString(strings.Join(words[:length], " ")) + "…"), true, nil
+}
+
+// stringText returns the text of a String node. The typographer extension
+// adds them with HTML entities such as &ldquo; as their value.
+func stringText(s *ast.String) string {
+	if s.IsCode() {
+		return html.UnescapeString(string(s.Value))
+	}
+	return string(s.Value)
+}
+
+// readingTime estimates minutes to read a number of words, rounding up.
+func readingTime(words int) int {
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
@@ -0,0 +1,120 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"sprout/internal/model"
+)
+
+// Feed is the data passed to feed templates. Links are absolute URLs.
+type Feed struct {
+	Title       string
+	Description string
+	Link        string
+	FeedLink    string
+	Updated     time.Time
+	Items       []Item
+	Site        *model.Site
+	Page        *model.Page
+}
+
+type Item struct {
+	Title   string
+	Link    string
+	Date    time.Time
+	Summary string
+	Page    *model.Page
+}
+
+// Format is an output format for feeds. The template is used unless the
+// site's templates directory has a file with the same name as Filename.
+type Format struct {
+	Name     string
+	Filename string
+	template string
+}
+
+var Formats = []Format{
+	{Name: "rss", Filename: "index.xml", template: rssTemplate},
+	{Name: "atom", Filename: "atom.xml", template: atomTemplate},
+	{Name: "json", Filename: "feed.json", template: jsonTemplate},
+}
+
+func Lookup(name string) (Format, bool) {
+	for _, format := range Formats {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+var funcs = template.FuncMap{
+	"xml":  escapeXML,
+	"json": marshalJSON,
+}
+
+type Renderer struct {
+	templates map[string]*template.Template
+}
+
+// NewRenderer parses the template for every feed format, preferring
+// templates/<filename> over the built-in one.
+func NewRenderer(templatesDir string) (*Renderer, error) {
+	r := &Renderer{templates: make(map[string]*template.Template, len(Formats))}
+
+	for _, format := range Formats {
+		text := format.template
+		overridePath := filepath.Join(templatesDir, format.Filename)
+		data, err := os.ReadFile(overridePath)
+		if err == nil {
+			text = string(data)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", overridePath, err)
+		}
+
+		t, err := template.New(format.Filename).Funcs(funcs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse feed template %s: %w", format.Filename, err)
+		}
+		r.templates[format.Name] = t
+	}
+
+	return r, nil
+}
+
+func (r *Renderer) Render(format string, feed *Feed) ([]byte, error) {
+	t, ok := r.templates[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown feed format %q", format)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, feed); err != nil {
+		return nil, fmt.Errorf("failed to render %s feed: %w", format, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func escapeXML(s string) (string, error) {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func marshalJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,72 @@
+package feed
+
+const rssTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>{{xml .Title}}</title>
+    <link>{{xml .Link}}</link>
+    <description>{{xml .Description}}</description>
+    <atom:link href="{{xml .FeedLink}}" rel="self" type="application/rss+xml"/>
+    {{- if not .Updated.IsZero}}
+    <lastBuildDate>{{.Updated.Format "Mon, 02 Jan 2006 15:04:05 -0700"}}</lastBuildDate>
+    {{- end}}
+    {{- range .Items}}
+    <item>
+      <title>{{xml .Title}}</title>
+      <link>{{xml .Link}}</link>
+      <guid>{{xml .Link}}</guid>
+      {{- if not .Date.IsZero}}
+      <pubDate>{{.Date.Format "Mon, 02 Jan 2006 15:04:05 -0700"}}</pubDate>
+      {{- end}}
+      <description>{{xml .Summary}}</description>
+    </item>
+    {{- end}}
+  </channel>
+</rss>
+`
+
+const atomTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>{{xml .Title}}</title>
+  {{- if .Description}}
+  <subtitle>{{xml .Description}}</subtitle>
+  {{- end}}
+  <link href="{{xml .Link}}"/>
+  <link href="{{xml .FeedLink}}" rel="self"/>
+  <id>{{xml .Link}}</id>
+  <updated>{{.Updated.Format "2006-01-02T15:04:05Z07:00"}}</updated>
+  {{- range .Items}}
+  <entry>
+    <title>{{xml .Title}}</title>
+    <link href="{{xml .Link}}"/>
+    <id>{{xml .Link}}</id>
+    <updated>{{.Date.Format "2006-01-02T15:04:05Z07:00"}}</updated>
+    <summary type="html">{{xml .Summary}}</summary>
+  </entry>
+  {{- end}}
+</feed>
+`
+
+const jsonTemplate = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": {{json .Title}},
+  "home_page_url": {{json .Link}},
+  "feed_url": {{json .FeedLink}},
+  {{- if .Description}}
+  "description": {{json .Description}},
+  {{- end}}
+  "items": [
+    {{- range $i, $item := .Items}}{{if $i}},{{end}}
+    {
+      "id": {{json $item.Link}},
+      "url": {{json $item.Link}},
+      "title": {{json $item.Title}},
+      {{- if not $item.Date.IsZero}}
+      "date_published": {{json ($item.Date.Format "2006-01-02T15:04:05Z07:00")}},
+      {{- end}}
+      "content_html": {{json $item.Summary}}
+    }
+    {{- end}}
+  ]
+}
+`
@@ -50,7 +50,7 @@ func Fingerprint(path string) (FileFingerprint, error) {
 		return FileFingerprint{}, err
 	}
 	return FileFingerprint{
-		MTime: info.ModTime().Unix(),
+		MTime: info.ModTime().UnixNano(),
 		Size:  info.Size(),
 	}, nil
 }
@@ -0,0 +1,84 @@
+package highlight
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Options controls how a code block is highlighted.
+type Options struct {
+	Style string
+	// Classes outputs CSS classes instead of inline styles; the stylesheet
+	// comes from WriteCSS.
+	Classes bool
+	// LineNumbers is "", "inline" or "table". Table puts the numbers in a
+	// separate column so they aren't copied with the code.
+	LineNumbers     string
+	LineNumberStart int
+	// HighlightLines are 1-based, inclusive line ranges.
+	HighlightLines [][2]int
+}
+
+// StyleExists reports whether name is a built-in Chroma style.
+func StyleExists(name string) bool {
+	_, ok := styles.Registry[strings.ToLower(name)]
+	return ok
+}
+
+// Styles returns the names of the built-in styles.
+func Styles() []string {
+	return styles.Names()
+}
+
+// Highlight writes code as highlighted HTML. It returns false without
+// writing anything if lang isn't a language Chroma knows.
+func Highlight(w io.Writer, code, lang string, opts Options) (bool, error) {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		return false, nil
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return false, fmt.Errorf("failed to highlight %s code: %w", lang, err)
+	}
+
+	// Chroma matches highlighted lines against the displayed line numbers,
+	// so they are shifted to start at LineNumberStart.
+	highlighted := opts.HighlightLines
+	if opts.LineNumberStart > 0 {
+		highlighted = make([][2]int, len(opts.HighlightLines))
+		for i, r := range opts.HighlightLines {
+			highlighted[i] = [2]int{r[0] + opts.LineNumberStart - 1, r[1] + opts.LineNumberStart - 1}
+		}
+	}
+
+	formatterOpts := []html.Option{
+		html.WithClasses(opts.Classes),
+		html.WithLineNumbers(opts.LineNumbers != ""),
+		html.LineNumbersInTable(opts.LineNumbers == "table"),
+		html.HighlightLines(highlighted),
+	}
+	if opts.LineNumberStart > 0 {
+		formatterOpts = append(formatterOpts, html.BaseLineNumber(opts.LineNumberStart))
+	}
+
+	if err := html.New(formatterOpts...).Format(w, styles.Get(opts.Style), iterator); err != nil {
+		return false, fmt.Errorf("failed to highlight %s code: %w", lang, err)
+	}
+	return true, nil
+}
+
+// WriteCSS writes the stylesheet for a style, for use with Options.Classes.
+func WriteCSS(w io.Writer, style string) error {
+	if !StyleExists(style) {
+		return fmt.Errorf("unknown highlight style %q", style)
+	}
+	return html.New(html.WithClasses(true)).WriteCSS(w, styles.Get(style))
+}
@@ -12,6 +12,9 @@ type Server struct {
 	RootDir        string
 	LiveReload     *LiveReload
 	LivereloadPort int
+	// BasePath serves the site under a path prefix such as /docs/v2, so
+	// that local previews use the same URLs as production.
+	BasePath string
 }
 
 func NewServer(rootDir string) *Server {
@@ -27,7 +30,20 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path := filepath.Clean(r.URL.Path)
+	urlPath := r.URL.Path
+	if s.BasePath != "" {
+		if urlPath == "/" {
+			http.Redirect(w, r, s.BasePath+"/", http.StatusFound)
+			return
+		}
+		if urlPath != s.BasePath && !strings.HasPrefix(urlPath, s.BasePath+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		urlPath = "/" + strings.TrimPrefix(urlPath[len(s.BasePath):], "/")
+	}
+
+	path := filepath.Clean(urlPath)
 	if strings.Contains(path, "..") {
 		http.NotFound(w, r)
 		return
@@ -63,6 +79,12 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sites built with pretty_urls = false link to /about.html, but allow
+	// the extension to be omitted like most static hosts do.
+	if filepath.Ext(path) == "" && tryPath(filepath.Join(s.RootDir, path+".html")) {
+		return
+	}
+
 	http.NotFound(w, r)
 }
 
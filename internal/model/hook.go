@@ -0,0 +1,24 @@
+package model
+
+import "html/template"
+
+// HookContext is passed to the render hook templates in templates/_markup/.
+// Which fields are set depends on the hook.
+type HookContext struct {
+	Page *Page
+	// Destination and Title are set for links and images.
+	Destination string
+	Title       string
+	// Text is the rendered content of a link or heading, or an image's alt
+	// text; PlainText is the same without markup.
+	Text      template.HTML
+	PlainText string
+	// Level and ID are set for headings.
+	Level int
+	ID    string
+	// Lang, Code and Attributes are set for fenced code blocks. Attributes
+	// holds the {key=value} options after the language.
+	Lang       string
+	Code       string
+	Attributes map[string]any
+}
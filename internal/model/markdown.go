@@ -0,0 +1,39 @@
+package model
+
+import "fmt"
+
+// MarkdownConfig turns on optional Markdown syntax and output settings. They
+// are set in the [markup] table and can be overridden per page with a
+// markup table in front matter.
+type MarkdownConfig struct {
+	Footnotes       bool `toml:"footnotes"`
+	DefinitionLists bool `toml:"definition_lists"`
+	Typographer     bool `toml:"typographer"`
+	Attributes      bool `toml:"attributes"`
+	HardWraps       bool `toml:"hard_wraps"`
+	XHTML           bool `toml:"xhtml"`
+}
+
+// Override returns c with the settings from a page's front matter, keyed by
+// their names in sprout.toml.
+func (c MarkdownConfig) Override(values map[string]bool) (MarkdownConfig, error) {
+	for key, value := range values {
+		switch key {
+		case "footnotes":
+			c.Footnotes = value
+		case "definition_lists":
+			c.DefinitionLists = value
+		case "typographer":
+			c.Typographer = value
+		case "attributes":
+			c.Attributes = value
+		case "hard_wraps":
+			c.HardWraps = value
+		case "xhtml":
+			c.XHTML = value
+		default:
+			return c, fmt.Errorf("unknown markup setting %q", key)
+		}
+	}
+	return c, nil
+}
@@ -1,18 +1,67 @@
 package model
 
-import "html/template"
+import (
+	"html/template"
+	"time"
+)
 
 type Site struct {
-	BaseURL string
+	BaseURL      string
+	Params       map[string]any
+	Pages        Pages
+	RegularPages Pages
+	Taxonomies   map[string]*Taxonomy
+}
+
+const (
+	KindHome     = "home"
+	KindSection  = "section"
+	KindPage     = "page"
+	KindTaxonomy = "taxonomy"
+	KindTerm     = "term"
+)
+
+type Taxonomy struct {
+	Name         string
+	Singular     string
+	RelPermalink string
+	Terms        Pages
 }
 
 type Page struct {
+	Kind         string
 	Title        string
 	Slug         string
 	Layout       string
 	RelPermalink string
 	ContentHTML  template.HTML
-	SourcePath   string
+	// TableOfContents is a nested list of links to the page's headings.
+	TableOfContents template.HTML
+	Headings        []Heading
+	// Summary is the content before a <!--more--> divider, or the start of
+	// the content as plain text. Truncated reports whether it is shorter
+	// than the full content.
+	Summary     template.HTML
+	Truncated   bool
+	WordCount   int
+	ReadingTime int
+	SourcePath  string
+	Date        time.Time
+	PublishDate time.Time
+	ExpiryDate  time.Time
+	Draft       bool
+	Weight      int
+	Params      map[string]any
+	Section     string
+	Parent      *Page
+	Pages       Pages
+}
+
+// Heading is a heading in a page's content, as listed in .Page.Headings.
+type Heading struct {
+	Level int
+	ID    string
+	Title string
 }
 
 type Paths struct {
@@ -23,10 +72,18 @@ type Paths struct {
 }
 
 type Config struct {
-	BaseURL    string `toml:"base_url"`
-	PrettyURLs bool   `toml:"pretty_urls"`
-	UnsafeHTML bool   `toml:"unsafe_html"`
-	Paths      struct {
+	BaseURL       string            `toml:"base_url"`
+	PrettyURLs    bool              `toml:"pretty_urls"`
+	UnsafeHTML    bool              `toml:"unsafe_html"`
+	Permalinks    map[string]string `toml:"permalinks"`
+	Params        map[string]any    `toml:"params"`
+	Taxonomies    map[string]string `toml:"taxonomies"`
+	Paginate      int               `toml:"paginate"`
+	Feeds         FeedsConfig       `toml:"feeds"`
+	Markup        MarkupConfig      `toml:"markup"`
+	SummaryLength int               `toml:"summary_length"`
+	BrokenRefs    string            `toml:"broken_refs"`
+	Paths         struct {
 		Content   string `toml:"content"`
 		Templates string `toml:"templates"`
 		Static    string `toml:"static"`
@@ -34,15 +91,63 @@ type Config struct {
 	} `toml:"paths"`
 }
 
+// FeedsConfig controls which list pages get feeds and in which formats.
+type FeedsConfig struct {
+	Formats  []string `toml:"formats"`
+	Limit    int      `toml:"limit"`
+	Home     bool     `toml:"home"`
+	Sections bool     `toml:"sections"`
+}
+
+type MarkupConfig struct {
+	MarkdownConfig
+	TOC       TOCConfig       `toml:"toc"`
+	Highlight HighlightConfig `toml:"highlight"`
+}
+
+// TOCConfig sets the heading levels included in .Page.TableOfContents and
+// .Page.Headings.
+type TOCConfig struct {
+	StartLevel int `toml:"start_level"`
+	EndLevel   int `toml:"end_level"`
+}
+
+// HighlightConfig controls syntax highlighting of fenced code blocks.
+// Classes outputs CSS classes instead of inline styles.
+type HighlightConfig struct {
+	Enabled     bool   `toml:"enabled"`
+	Style       string `toml:"style"`
+	Classes     bool   `toml:"classes"`
+	LineNumbers bool   `toml:"line_numbers"`
+}
+
 type ResolvedConfig struct {
-	BaseURL    string
-	PrettyURLs bool
-	UnsafeHTML bool
-	Paths      Paths
+	BaseURL       string
+	BasePath      string
+	PrettyURLs    bool
+	UnsafeHTML    bool
+	Permalinks    map[string]string
+	Params        map[string]any
+	Taxonomies    map[string]string
+	Paginate      int
+	Feeds         FeedsConfig
+	Markup        MarkupConfig
+	SummaryLength int
+	BrokenRefs    string
+	Paths         Paths
 }
 
 type FrontMatter struct {
-	Title  string
-	Slug   string
-	Layout string
+	Title       string
+	Slug        string
+	Layout      string
+	Date        time.Time
+	PublishDate time.Time
+	ExpiryDate  time.Time
+	Draft       bool
+	Weight      int
+	Paginate    int
+	// Markup overrides the site's Markdown settings for the page.
+	Markup map[string]bool
+	Params map[string]any
 }
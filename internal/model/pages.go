@@ -0,0 +1,64 @@
+package model
+
+import (
+	"sort"
+	"strings"
+)
+
+type Pages []*Page
+
+// ByWeight sorts by weight, with unweighted pages last, then newest first,
+// then by title. It is the order used for .Pages and .Site.Pages.
+func (p Pages) ByWeight() Pages {
+	return p.sorted(func(a, b *Page) bool {
+		if a.Weight != b.Weight {
+			if a.Weight == 0 || b.Weight == 0 {
+				return b.Weight == 0
+			}
+			return a.Weight < b.Weight
+		}
+		if !a.Date.Equal(b.Date) {
+			return a.Date.After(b.Date)
+		}
+		return lessTitle(a, b)
+	})
+}
+
+// ByDate sorts oldest first; use .ByDate.Reverse for newest first.
+func (p Pages) ByDate() Pages {
+	return p.sorted(func(a, b *Page) bool {
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return lessTitle(a, b)
+	})
+}
+
+func (p Pages) ByTitle() Pages {
+	return p.sorted(lessTitle)
+}
+
+func (p Pages) Reverse() Pages {
+	reversed := make(Pages, len(p))
+	for i, page := range p {
+		reversed[len(p)-1-i] = page
+	}
+	return reversed
+}
+
+func (p Pages) sorted(less func(a, b *Page) bool) Pages {
+	sorted := make(Pages, len(p))
+	copy(sorted, p)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+func lessTitle(a, b *Page) bool {
+	at, bt := strings.ToLower(a.Title), strings.ToLower(b.Title)
+	if at != bt {
+		return at < bt
+	}
+	return a.RelPermalink < b.RelPermalink
+}
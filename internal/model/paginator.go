@@ -0,0 +1,50 @@
+package model
+
+// Pager is one page of a paginated list, as linked from page navigation.
+type Pager struct {
+	PageNumber   int
+	RelPermalink string
+}
+
+// Paginator is passed to templates as .Paginator when a list page is split
+// across several outputs. Pages holds the slice for the page being rendered.
+type Paginator struct {
+	Pages      Pages
+	PageNumber int
+	PageSize   int
+	TotalPages int
+	TotalItems int
+	Pagers     []Pager
+}
+
+func (p *Paginator) HasPrev() bool {
+	return p.PageNumber > 1
+}
+
+func (p *Paginator) HasNext() bool {
+	return p.PageNumber < p.TotalPages
+}
+
+// Prev returns the previous pager, or nil on the first page.
+func (p *Paginator) Prev() *Pager {
+	if !p.HasPrev() {
+		return nil
+	}
+	return &p.Pagers[p.PageNumber-2]
+}
+
+// Next returns the next pager, or nil on the last page.
+func (p *Paginator) Next() *Pager {
+	if !p.HasNext() {
+		return nil
+	}
+	return &p.Pagers[p.PageNumber]
+}
+
+func (p *Paginator) First() *Pager {
+	return &p.Pagers[0]
+}
+
+func (p *Paginator) Last() *Pager {
+	return &p.Pagers[len(p.Pagers)-1]
+}
@@ -0,0 +1,38 @@
+package model
+
+import "html/template"
+
+// Shortcode is passed to a shortcode template for each call in a page's
+// content, such as {{< figure src="a.png" >}}.
+type Shortcode struct {
+	Name string
+	// Params holds named arguments and Positional the unnamed ones. A call
+	// uses one kind or the other.
+	Params     map[string]string
+	Positional []string
+	// Inner is the content between an opening and a closing tag. With
+	// {{% %}} delimiters it has been rendered from Markdown.
+	Inner template.HTML
+	Page  *Page
+	// Ordinal counts the calls of this shortcode in the page, from 0.
+	Ordinal int
+}
+
+// Get returns an argument by position or by name, or "" if there is none:
+// {{.Get 0}} or {{.Get "src"}}.
+func (s *Shortcode) Get(key any) string {
+	switch k := key.(type) {
+	case int:
+		if k >= 0 && k < len(s.Positional) {
+			return s.Positional[k]
+		}
+	case string:
+		return s.Params[k]
+	}
+	return ""
+}
+
+// IsNamedParams reports whether the call used named arguments.
+func (s *Shortcode) IsNamedParams() bool {
+	return len(s.Params) > 0
+}
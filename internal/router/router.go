@@ -1,50 +1,230 @@
 package router
 
 import (
+	"fmt"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"sprout/internal/logx"
+	"sprout/internal/model"
 )
 
-func RelPermalink(sourcePath, slug string) string {
+type Options struct {
+	PrettyURLs bool
+	Permalinks map[string]string
+	// BasePath is the path component of base_url without a trailing slash,
+	// e.g. /docs/v2. Every permalink starts with it.
+	BasePath string
+}
+
+// RelPermalink returns the URL of a content file. Pages in a section with a
+// pattern in [permalinks] use that pattern; everything else, and any page
+// the pattern can't be expanded for, falls back to its file path with the
+// filename replaced by the slug.
+func RelPermalink(sourcePath string, fm *model.FrontMatter, opts Options) string {
+	return withBasePath(relPermalink(sourcePath, fm, opts), opts)
+}
+
+func relPermalink(sourcePath string, fm *model.FrontMatter, opts Options) string {
+	relPath := filepath.ToSlash(sourcePath)
+	if strings.HasPrefix(relPath, "content/") {
+		relPath = strings.TrimPrefix(relPath, "content/")
+	}
+
+	dir := path.Dir(relPath)
+	if dir == "." {
+		dir = ""
+	}
+
+	filename := path.Base(relPath)
+	if idx := strings.LastIndex(filename, "."); idx != -1 {
+		filename = filename[:idx]
+	}
+
+	slug := fm.Slug
+	if slug == "" && filename != "index" {
+		slug = filename
+	}
+
 	if slug == "" {
-		relPath := sourcePath
-		if strings.HasPrefix(relPath, "content/") {
-			relPath = strings.TrimPrefix(relPath, "content/")
-		}
-		dir := filepath.Dir(relPath)
-		if dir == "." || dir == "" {
+		if dir == "" {
 			return "/"
 		}
-		dir = filepath.ToSlash(dir)
 		return "/" + dir + "/"
 	}
 
-	relPath := sourcePath
-	if strings.HasPrefix(relPath, "content/") {
-		relPath = strings.TrimPrefix(relPath, "content/")
+	section := dir
+	if idx := strings.Index(section, "/"); idx != -1 {
+		section = section[:idx]
+	}
+
+	if pattern, ok := opts.Permalinks[section]; ok && section != "" {
+		expanded, err := expandPattern(pattern, permalinkValues(dir, section, filename, slug, fm))
+		if err == nil {
+			return finishURL(expanded, opts.PrettyURLs)
+		}
+		logx.Warnf("permalink pattern %q for %s: %v, using file path", pattern, sourcePath, err)
 	}
 
-	if idx := strings.LastIndex(relPath, "."); idx != -1 {
-		relPath = relPath[:idx]
+	if dir == "" {
+		return pageURL("/"+slug, opts.PrettyURLs)
 	}
 
-	dir := filepath.Dir(relPath)
-	dir = filepath.ToSlash(dir)
+	return pageURL("/"+dir+"/"+slug, opts.PrettyURLs)
+}
 
-	if dir == "." || dir == "" {
-		return "/" + slug + "/"
+func permalinkValues(dir, section, filename, slug string, fm *model.FrontMatter) map[string]string {
+	values := map[string]string{
+		"section":  section,
+		"sections": dir,
+		"filename": filename,
+		"slug":     slug,
+	}
+	if !fm.Date.IsZero() {
+		values["year"] = fm.Date.Format("2006")
+		values["month"] = fm.Date.Format("01")
+		values["day"] = fm.Date.Format("02")
 	}
+	return values
+}
+
+func expandPattern(pattern string, values map[string]string) (string, error) {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		var buf strings.Builder
+		for segment != "" {
+			idx := strings.Index(segment, ":")
+			if idx == -1 {
+				buf.WriteString(segment)
+				break
+			}
+			buf.WriteString(segment[:idx])
+			segment = segment[idx+1:]
 
-	return "/" + dir + "/" + slug + "/"
+			end := 0
+			for end < len(segment) && isPlaceholderChar(segment[end]) {
+				end++
+			}
+			name := segment[:end]
+			segment = segment[end:]
+
+			value, ok := values[name]
+			if !ok {
+				if name == "year" || name == "month" || name == "day" {
+					return "", fmt.Errorf("page has no date for :%s", name)
+				}
+				return "", fmt.Errorf("unknown placeholder :%s", name)
+			}
+			buf.WriteString(value)
+		}
+		segments[i] = buf.String()
+	}
+
+	expanded := path.Clean("/" + strings.Join(segments, "/"))
+	if strings.HasSuffix(pattern, "/") && expanded != "/" {
+		expanded += "/"
+	}
+	return expanded, nil
 }
 
-func OutputPath(publicDir, relPermalink string) string {
+func isPlaceholderChar(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+func finishURL(expanded string, prettyURLs bool) string {
+	if prettyURLs || !strings.HasSuffix(expanded, "/") || expanded == "/" {
+		return expanded
+	}
+	return strings.TrimSuffix(expanded, "/") + ".html"
+}
+
+func TaxonomyPermalink(taxonomy string, opts Options) string {
+	return withBasePath("/"+taxonomy+"/", opts)
+}
+
+func TermPermalink(taxonomy, term string, opts Options) string {
+	return withBasePath(pageURL("/"+taxonomy+"/"+term, opts.PrettyURLs), opts)
+}
+
+// PagerPermalink returns the URL of page n of a paginated list. The first
+// page is the list itself; later pages live below it in page/<n>.
+func PagerPermalink(listPermalink string, n int, opts Options) string {
+	if n <= 1 {
+		return listPermalink
+	}
+	base := strings.TrimSuffix(strings.TrimSuffix(listPermalink, "/"), ".html")
+	return pageURL(fmt.Sprintf("%s/page/%d", base, n), opts.PrettyURLs)
+}
+
+// FeedPermalink returns the URL of a feed file that belongs to a list page,
+// e.g. /blog/index.xml for /blog/.
+func FeedPermalink(listPermalink, filename string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(listPermalink, "/"), ".html")
+	return base + "/" + filename
+}
+
+// RelURL turns a path relative to the site root into a URL under the base
+// path: with base_url https://example.org/docs/, "css/site.css" and
+// "/css/site.css" both become /docs/css/site.css. Absolute URLs and paths
+// that already start with the base path are returned unchanged, so that
+// templates can pass .Page.RelPermalink through relURL.
+func RelURL(p string, opts Options) string {
+	if strings.Contains(p, "://") || strings.HasPrefix(p, "//") {
+		return p
+	}
+	if opts.BasePath != "" && (p == opts.BasePath || strings.HasPrefix(p, opts.BasePath+"/")) {
+		return p
+	}
+	return withBasePath(p, opts)
+}
+
+// withBasePath prefixes a site path with the base path. Unlike RelURL it
+// always adds it, since a section can have the same name as the base path:
+// content/docs/intro.md is /docs/docs/intro/ under base_url
+// https://example.org/docs/.
+func withBasePath(p string, opts Options) string {
+	return opts.BasePath + "/" + strings.TrimPrefix(p, "/")
+}
+
+// AbsURL joins the scheme and host of the site's base URL with a permalink,
+// which already carries the base path.
+func AbsURL(baseURL, relPermalink string) string {
+	if strings.Contains(relPermalink, "://") {
+		return relPermalink
+	}
+	origin := strings.TrimSuffix(baseURL, "/")
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		origin = u.Scheme + "://" + u.Host
+	}
+	return origin + "/" + strings.TrimPrefix(relPermalink, "/")
+}
+
+func pageURL(path string, prettyURLs bool) string {
+	if prettyURLs {
+		return path + "/"
+	}
+	return path + ".html"
+}
+
+// OutputPath returns the file a permalink is written to. URLs ending in a
+// file name such as /about.html or /blog/index.xml are written as is;
+// everything else gets an index.html. The base path is not part of the
+// output path: public/ is the root of the site wherever it is served from.
+func OutputPath(publicDir, relPermalink string, opts Options) string {
+	if opts.BasePath != "" && (relPermalink == opts.BasePath || strings.HasPrefix(relPermalink, opts.BasePath+"/")) {
+		relPermalink = "/" + strings.TrimPrefix(relPermalink[len(opts.BasePath):], "/")
+	}
 	if relPermalink == "/" {
 		return filepath.Join(publicDir, "index.html")
 	}
 
-	path := strings.Trim(relPermalink, "/")
-	return filepath.Join(publicDir, path, "index.html")
+	rel := strings.Trim(relPermalink, "/")
+	if !strings.HasSuffix(relPermalink, "/") && path.Ext(rel) != "" {
+		return filepath.Join(publicDir, filepath.FromSlash(rel))
+	}
+	return filepath.Join(publicDir, filepath.FromSlash(rel), "index.html")
 }
 
 func SimpleRelPermalink(slug string) string {
@@ -0,0 +1,109 @@
+package sitemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"sprout/internal/model"
+)
+
+const (
+	SitemapFile = "sitemap.xml"
+	RobotsFile  = "robots.txt"
+)
+
+// Sitemap is the data passed to the sitemap.xml and robots.txt templates.
+// Links are absolute URLs.
+type Sitemap struct {
+	Entries     []Entry
+	SitemapLink string
+	Site        *model.Site
+}
+
+type Entry struct {
+	Loc     string
+	LastMod time.Time
+	Page    *model.Page
+}
+
+const sitemapTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  {{- range .Entries}}
+  <url>
+    <loc>{{xml .Loc}}</loc>
+    {{- if not .LastMod.IsZero}}
+    <lastmod>{{.LastMod.Format "2006-01-02T15:04:05Z07:00"}}</lastmod>
+    {{- end}}
+  </url>
+  {{- end}}
+</urlset>
+`
+
+const robotsTemplate = `User-agent: *
+Allow: /
+
+Sitemap: {{.SitemapLink}}
+`
+
+var funcs = template.FuncMap{
+	"xml": escapeXML,
+}
+
+type Renderer struct {
+	templates map[string]*template.Template
+}
+
+// NewRenderer parses the sitemap.xml and robots.txt templates, preferring
+// files of the same name in the templates directory over the built-in ones.
+func NewRenderer(templatesDir string) (*Renderer, error) {
+	r := &Renderer{templates: make(map[string]*template.Template, 2)}
+
+	defaults := map[string]string{
+		SitemapFile: sitemapTemplate,
+		RobotsFile:  robotsTemplate,
+	}
+	for name, text := range defaults {
+		overridePath := filepath.Join(templatesDir, name)
+		data, err := os.ReadFile(overridePath)
+		if err == nil {
+			text = string(data)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", overridePath, err)
+		}
+
+		t, err := template.New(name).Funcs(funcs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		r.templates[name] = t
+	}
+
+	return r, nil
+}
+
+// Render executes the template for name, which is SitemapFile or RobotsFile.
+func (r *Renderer) Render(name string, sitemap *Sitemap) ([]byte, error) {
+	t, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown sitemap template %q", name)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, sitemap); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func escapeXML(s string) (string, error) {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,348 @@
+package template
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Group is one group returned by the group function.
+type Group struct {
+	Key   any
+	Items any
+}
+
+// where keeps the items of a collection whose key matches a value:
+//
+//	{{where .Site.Pages "Section" "blog"}}
+//	{{where .Site.Pages "Weight" ">" 0}}
+//	{{where .Site.Pages "Params.featured" true}}
+//
+// key is a dotted path of fields, map keys and methods without arguments.
+// The operators are =, ==, !=, <, <=, >, >= and in. Items without the key
+// never match. The result has the same type as the collection, so
+// {{(where .Site.Pages "Section" "blog").ByDate}} works.
+func where(collection any, key string, args ...any) (any, error) {
+	var op string
+	var match any
+	switch len(args) {
+	case 1:
+		op, match = "==", args[0]
+	case 2:
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("where: operator must be a string, got %T", args[0])
+		}
+		op, match = s, args[1]
+	default:
+		return nil, fmt.Errorf("where: expected a value or an operator and a value, got %d arguments", len(args))
+	}
+
+	items, err := sliceValue("where", collection)
+	if err != nil || !items.IsValid() {
+		return nil, err
+	}
+
+	result := reflect.MakeSlice(items.Type(), 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		value, ok := lookup(items.Index(i), key)
+		if !ok {
+			continue
+		}
+		matched, err := matches(value, op, reflect.ValueOf(match))
+		if err != nil {
+			return nil, fmt.Errorf("where %s: %w", key, err)
+		}
+		if matched {
+			result = reflect.Append(result, items.Index(i))
+		}
+	}
+	return result.Interface(), nil
+}
+
+func matches(value reflect.Value, op string, match reflect.Value) (bool, error) {
+	if op == "in" {
+		list := indirect(match)
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			return false, fmt.Errorf("in expects a list, got %s", list.Kind())
+		}
+		for i := 0; i < list.Len(); i++ {
+			c, err := compare(value, list.Index(i))
+			if err == nil && c == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	c, err := compare(value, match)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "=", "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// sortBy sorts a collection by a key, ascending unless the order is "desc".
+// Items without the key go last. The sort is stable.
+func sortBy(collection any, key string, order ...string) (any, error) {
+	desc := false
+	if len(order) > 0 {
+		switch strings.ToLower(order[0]) {
+		case "asc":
+		case "desc":
+			desc = true
+		default:
+			return nil, fmt.Errorf("sortBy: order must be asc or desc, got %q", order[0])
+		}
+	}
+
+	items, err := sliceValue("sortBy", collection)
+	if err != nil || !items.IsValid() {
+		return nil, err
+	}
+
+	keys := make([]reflect.Value, items.Len())
+	present := make([]bool, items.Len())
+	indexes := make([]int, items.Len())
+	for i := range keys {
+		keys[i], present[i] = lookup(items.Index(i), key)
+		indexes[i] = i
+	}
+
+	var sortErr error
+	sort.SliceStable(indexes, func(a, b int) bool {
+		i, j := indexes[a], indexes[b]
+		if !present[i] || !present[j] {
+			return present[i] && !present[j]
+		}
+		c, err := compare(keys[i], keys[j])
+		if err != nil {
+			sortErr = err
+			return false
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	if sortErr != nil {
+		return nil, fmt.Errorf("sortBy %s: %w", key, sortErr)
+	}
+
+	result := reflect.MakeSlice(items.Type(), 0, items.Len())
+	for _, i := range indexes {
+		result = reflect.Append(result, items.Index(i))
+	}
+	return result.Interface(), nil
+}
+
+// first returns the first n items of a collection.
+func first(n int, collection any) (any, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("first: count must not be negative, got %d", n)
+	}
+
+	items, err := sliceValue("first", collection)
+	if err != nil || !items.IsValid() {
+		return nil, err
+	}
+	if n > items.Len() {
+		n = items.Len()
+	}
+	return items.Slice(0, n).Interface(), nil
+}
+
+// group splits a collection by the value of a key, keeping groups in the
+// order their first item appears: {{range group .Page.Pages "Date.Year"}}.
+// Items without the key are left out.
+func group(collection any, key string) ([]Group, error) {
+	items, err := sliceValue("group", collection)
+	if err != nil || !items.IsValid() {
+		return nil, err
+	}
+
+	var groups []Group
+	var members []reflect.Value
+	for i := 0; i < items.Len(); i++ {
+		value, ok := lookup(items.Index(i), key)
+		if !ok {
+			continue
+		}
+		groupKey := value.Interface()
+
+		found := -1
+		for g := range groups {
+			if c, err := compare(reflect.ValueOf(groups[g].Key), value); err == nil && c == 0 {
+				found = g
+				break
+			}
+		}
+		if found == -1 {
+			groups = append(groups, Group{Key: groupKey})
+			members = append(members, reflect.MakeSlice(items.Type(), 0, 1))
+			found = len(groups) - 1
+		}
+		members[found] = reflect.Append(members[found], items.Index(i))
+	}
+
+	for g := range groups {
+		groups[g].Items = members[g].Interface()
+	}
+	return groups, nil
+}
+
+func sliceValue(name string, collection any) (reflect.Value, error) {
+	if collection == nil {
+		return reflect.Value{}, nil
+	}
+	v := reflect.ValueOf(collection)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return reflect.Value{}, fmt.Errorf("%s: expected a list, got %T", name, collection)
+	}
+	if v.Kind() == reflect.Array {
+		s := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), v.Len(), v.Len())
+		reflect.Copy(s, v)
+		v = s
+	}
+	return v, nil
+}
+
+// lookup follows a dotted path of struct fields, map keys and methods that
+// take no arguments and return one value, such as "Params.author" or
+// "Date.Year".
+func lookup(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		if !v.IsValid() {
+			return reflect.Value{}, false
+		}
+
+		if method := v.MethodByName(name); method.IsValid() && method.Type().NumIn() == 0 && method.Type().NumOut() == 1 {
+			if v.Kind() == reflect.Pointer && v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = method.Call(nil)[0]
+			continue
+		}
+
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field, ok := v.Type().FieldByName(name)
+			if !ok || !field.IsExported() {
+				return reflect.Value{}, false
+			}
+			v = v.FieldByIndex(field.Index)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, false
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return reflect.Value{}, false
+			}
+		default:
+			return reflect.Value{}, false
+		}
+	}
+
+	v = indirect(v)
+	return v, v.IsValid()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// compare orders two values of the same kind. Integers and floats can be
+// mixed; other kinds have to match.
+func compare(a, b reflect.Value) (int, error) {
+	a, b = indirect(a), indirect(b)
+	if !a.IsValid() || !b.IsValid() {
+		return 0, fmt.Errorf("cannot compare nil values")
+	}
+
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time)), nil
+	}
+
+	switch {
+	case isInt(a) && isInt(b):
+		return cmp.Compare(a.Int(), b.Int()), nil
+	case isNumber(a) && isNumber(b):
+		return cmp.Compare(toFloat(a), toFloat(b)), nil
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return strings.Compare(a.String(), b.String()), nil
+	case a.Kind() == reflect.Bool && b.Kind() == reflect.Bool:
+		if a.Bool() == b.Bool() {
+			return 0, nil
+		}
+		if !a.Bool() {
+			return -1, nil
+		}
+		return 1, nil
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", a.Type(), b.Type())
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isNumber(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return isInt(v)
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case isInt(v):
+		return float64(v.Int())
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float()
+	}
+	return float64(v.Uint())
+}
@@ -0,0 +1,158 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"sprout/internal/content"
+	"sprout/internal/model"
+	"sprout/internal/router"
+)
+
+// funcMap returns the functions available to page templates. URL functions
+// add the path of base_url, so sites served from a subdirectory can link to
+// static files the same way as sites served from the root.
+func funcMap(resolved *model.ResolvedConfig) template.FuncMap {
+	opts := router.Options{
+		PrettyURLs: resolved.PrettyURLs,
+		Permalinks: resolved.Permalinks,
+		BasePath:   resolved.BasePath,
+	}
+
+	return template.FuncMap{
+		"relURL": func(p string) string {
+			return router.RelURL(p, opts)
+		},
+		"absURL": func(p string) string {
+			return router.AbsURL(resolved.BaseURL, router.RelURL(p, opts))
+		},
+		"dateFormat": dateFormat,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"truncate":   truncate,
+		"slugify":    content.Slugify,
+		"where":      where,
+		"sortBy":     sortBy,
+		"first":      first,
+		"group":      group,
+		"markdownify": func(s string) (template.HTML, error) {
+			return markdownify(s, resolved.UnsafeHTML)
+		},
+		"safeHTML": safeHTML,
+		"jsonify":  jsonify,
+		"dict":     dict,
+		"slice":    slice,
+	}
+}
+
+// dateFormat formats a time.Time, or a date string in any format accepted in
+// front matter, with a Go layout: {{dateFormat "Jan 2, 2006" .Page.Date}}.
+func dateFormat(layout string, value any) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.Format(layout), nil
+	case string:
+		t, err := content.ParseDate(v)
+		if err != nil {
+			return "", fmt.Errorf("dateFormat: %w", err)
+		}
+		return t.Format(layout), nil
+	}
+	return "", fmt.Errorf("dateFormat: expected a date, got %T", value)
+}
+
+// title upper-cases the first letter of every word.
+func title(s string) string {
+	var buf strings.Builder
+	startOfWord := true
+	for _, r := range s {
+		if startOfWord {
+			buf.WriteRune(unicode.ToTitle(r))
+		} else {
+			buf.WriteRune(r)
+		}
+		startOfWord = unicode.IsSpace(r)
+	}
+	return buf.String()
+}
+
+// truncate shortens s to at most length characters, cutting at the last
+// word boundary when there is one, and marks the cut with an ellipsis.
+func truncate(length int, s string) string {
+	if length < 0 || utf8.RuneCountInString(s) <= length {
+		return s
+	}
+
+	runes := []rune(s)
+	cut := string(runes[:length])
+	if idx := strings.LastIndexFunc(cut, unicode.IsSpace); idx > 0 {
+		cut = cut[:idx]
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// markdownify renders a Markdown string, such as a front matter param. A
+// single paragraph is returned without its <p> tags so the result can be
+// used inline.
+func markdownify(s string, unsafeHTML bool) (template.HTML, error) {
+	html, _, err := content.RenderMarkdown([]byte(s), unsafeHTML)
+	if err != nil {
+		return "", fmt.Errorf("markdownify: %w", err)
+	}
+
+	html = bytes.TrimSpace(html)
+	if bytes.HasPrefix(html, []byte("<p>")) && bytes.HasSuffix(html, []byte("</p>")) && bytes.Count(html, []byte("<p>")) == 1 {
+		html = html[len("<p>") : len(html)-len("</p>")]
+	}
+	return template.HTML(html), nil
+}
+
+// safeHTML marks a string as trusted HTML so it isn't escaped.
+func safeHTML(s string) template.HTML {
+	return template.HTML(s)
+}
+
+// jsonify encodes a value as JSON. The result is inserted as is in script
+// elements, including <script type="application/ld+json">.
+func jsonify(v any) (template.JS, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("jsonify: %w", err)
+	}
+	return template.JS(data), nil
+}
+
+// dict builds a map from alternating keys and values, mostly to pass
+// several values to a template: {{template "card" dict "Page" . "Wide" true}}.
+func dict(values ...any) (map[string]any, error) {
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("dict: expected key and value pairs, got %d arguments", len(values))
+	}
+
+	m := make(map[string]any, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: keys must be strings, got %T", values[i])
+		}
+		m[key] = values[i+1]
+	}
+	return m, nil
+}
+
+func slice(items ...any) []any {
+	return items
+}
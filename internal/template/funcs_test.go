@@ -0,0 +1,349 @@
+package template
+
+import (
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sprout/internal/model"
+)
+
+func testConfig() *model.ResolvedConfig {
+	return &model.ResolvedConfig{
+		BaseURL:    "https://example.org/docs/",
+		BasePath:   "/docs",
+		PrettyURLs: true,
+	}
+}
+
+func testPages() model.Pages {
+	return model.Pages{
+		{Title: "Go", Section: "blog", Weight: 2, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Params: map[string]any{"featured": true}},
+		{Title: "About", Section: "", Weight: 0, Date: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Params: map[string]any{}},
+		{Title: "Rust", Section: "blog", Weight: 1, Date: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), Params: map[string]any{"featured": false}},
+	}
+}
+
+func titles(t *testing.T, v any) []string {
+	t.Helper()
+	pages, ok := v.(model.Pages)
+	if !ok {
+		t.Fatalf("got %T, want model.Pages", v)
+	}
+	var result []string
+	for _, page := range pages {
+		result = append(result, page.Title)
+	}
+	return result
+}
+
+func execute(t *testing.T, text string, data any) string {
+	t.Helper()
+	tmpl, err := template.New("test").Funcs(funcMap(testConfig())).Parse(text)
+	if err != nil {
+		t.Fatalf("parse %q: %v", text, err)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("execute %q: %v", text, err)
+	}
+	return buf.String()
+}
+
+func TestRelURL(t *testing.T) {
+	tests := map[string]string{
+		`{{relURL "style.css"}}`:             "/docs/style.css",
+		`{{relURL "/style.css"}}`:            "/docs/style.css",
+		`{{relURL "/docs/style.css"}}`:       "/docs/style.css",
+		`{{relURL "https://cdn.example/x"}}`: "https://cdn.example/x",
+	}
+	for text, want := range tests {
+		if got := execute(t, text, nil); got != want {
+			t.Errorf("%s = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestAbsURL(t *testing.T) {
+	tests := map[string]string{
+		`{{absURL "style.css"}}`:   "https://example.org/docs/style.css",
+		`{{absURL "/blog/"}}`:      "https://example.org/docs/blog/",
+		`{{absURL "/docs/blog/"}}`: "https://example.org/docs/blog/",
+	}
+	for text, want := range tests {
+		if got := execute(t, text, nil); got != want {
+			t.Errorf("%s = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestDateFormat(t *testing.T) {
+	date := time.Date(2025, 1, 15, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{date, "Jan 15, 2025"},
+		{&date, "Jan 15, 2025"},
+		{"2025-01-15", "Jan 15, 2025"},
+		{"2025-01-15T09:30:00Z", "Jan 15, 2025"},
+	}
+	for _, tt := range tests {
+		got, err := dateFormat("Jan 2, 2006", tt.value)
+		if err != nil {
+			t.Errorf("dateFormat(%v): %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("dateFormat(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	if _, err := dateFormat("2006", "not a date"); err == nil {
+		t.Error("dateFormat with an invalid string: expected an error")
+	}
+	if _, err := dateFormat("2006", 42); err == nil {
+		t.Error("dateFormat with an int: expected an error")
+	}
+}
+
+func TestLower(t *testing.T) {
+	if got := execute(t, `{{lower "Hello World"}}`, nil); got != "hello world" {
+		t.Errorf("lower = %q", got)
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := map[string]string{
+		"hello world":     "Hello World",
+		"already Title":   "Already Title",
+		"élan vital":      "Élan Vital",
+		"  spaced  words": "  Spaced  Words",
+		"":                "",
+	}
+	for in, want := range tests {
+		if got := title(in); got != want {
+			t.Errorf("title(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		length int
+		in     string
+		want   string
+	}{
+		{20, "short", "short"},
+		{5, "exact", "exact"},
+		{12, "Hello wonderful world", "Hello…"},
+		{8, "Hello, world", "Hello…"},
+		{4, "abcdefgh", "abcd…"},
+		{3, "héllo", "hél…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.length, tt.in); got != tt.want {
+			t.Errorf("truncate(%d, %q) = %q, want %q", tt.length, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	if got := execute(t, `{{slugify "Hello, World!"}}`, nil); got != "hello-world" {
+		t.Errorf("slugify = %q", got)
+	}
+}
+
+func TestWhere(t *testing.T) {
+	pages := testPages()
+	tests := []struct {
+		key  string
+		args []any
+		want []string
+	}{
+		{"Section", []any{"blog"}, []string{"Go", "Rust"}},
+		{"Section", []any{"!=", "blog"}, []string{"About"}},
+		{"Weight", []any{">", 1}, []string{"Go"}},
+		{"Weight", []any{">=", 1.0}, []string{"Go", "Rust"}},
+		{"Params.featured", []any{true}, []string{"Go"}},
+		{"Title", []any{"in", []string{"About", "Rust"}}, []string{"About", "Rust"}},
+		{"Date.Year", []any{2023}, []string{"About", "Rust"}},
+	}
+	for _, tt := range tests {
+		got, err := where(pages, tt.key, tt.args...)
+		if err != nil {
+			t.Errorf("where %s %v: %v", tt.key, tt.args, err)
+			continue
+		}
+		if names := titles(t, got); !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("where %s %v = %v, want %v", tt.key, tt.args, names, tt.want)
+		}
+	}
+
+	if _, err := where(pages, "Title", 42); err == nil {
+		t.Error("where comparing a string with an int: expected an error")
+	}
+	if _, err := where(pages, "Title", "~", "Go"); err == nil {
+		t.Error("where with an unknown operator: expected an error")
+	}
+	if _, err := where("not a list", "Title", "Go"); err == nil {
+		t.Error("where on a string: expected an error")
+	}
+
+	maps := []map[string]any{{"name": "a", "n": 1}, {"name": "b", "n": 2}}
+	got, err := where(maps, "n", 2)
+	if err != nil {
+		t.Fatalf("where on maps: %v", err)
+	}
+	if want := maps[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("where on maps = %v, want %v", got, want)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	pages := testPages()
+	tests := []struct {
+		key   string
+		order []string
+		want  []string
+	}{
+		{"Title", nil, []string{"About", "Go", "Rust"}},
+		{"Title", []string{"desc"}, []string{"Rust", "Go", "About"}},
+		{"Date", []string{"asc"}, []string{"About", "Rust", "Go"}},
+		{"Weight", nil, []string{"About", "Rust", "Go"}},
+		{"Params.featured", nil, []string{"Rust", "Go", "About"}},
+	}
+	for _, tt := range tests {
+		got, err := sortBy(pages, tt.key, tt.order...)
+		if err != nil {
+			t.Errorf("sortBy %s %v: %v", tt.key, tt.order, err)
+			continue
+		}
+		if names := titles(t, got); !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("sortBy %s %v = %v, want %v", tt.key, tt.order, names, tt.want)
+		}
+	}
+
+	if names := titles(t, pages); !reflect.DeepEqual(names, []string{"Go", "About", "Rust"}) {
+		t.Errorf("sortBy modified its input: %v", names)
+	}
+	if _, err := sortBy(pages, "Title", "sideways"); err == nil {
+		t.Error("sortBy with an invalid order: expected an error")
+	}
+}
+
+func TestFirst(t *testing.T) {
+	pages := testPages()
+	got, err := first(2, pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := titles(t, got); !reflect.DeepEqual(names, []string{"Go", "About"}) {
+		t.Errorf("first 2 = %v", names)
+	}
+
+	got, err = first(10, pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := titles(t, got); len(names) != 3 {
+		t.Errorf("first 10 = %v, want all 3 pages", names)
+	}
+
+	if _, err := first(-1, pages); err == nil {
+		t.Error("first -1: expected an error")
+	}
+}
+
+func TestGroup(t *testing.T) {
+	groups, err := group(testPages(), "Date.Year")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	if groups[0].Key != 2024 || groups[1].Key != 2023 {
+		t.Errorf("group keys = %v, %v, want 2024, 2023", groups[0].Key, groups[1].Key)
+	}
+	if names := titles(t, groups[1].Items); !reflect.DeepEqual(names, []string{"About", "Rust"}) {
+		t.Errorf("2023 group = %v", names)
+	}
+
+	got := execute(t, `{{range group . "Section"}}[{{.Key}}:{{range .Items}}{{.Title}} {{end}}]{{end}}`, testPages())
+	if want := "[blog:Go Rust ][:About ]"; got != want {
+		t.Errorf("group in a template = %q, want %q", got, want)
+	}
+}
+
+func TestMarkdownify(t *testing.T) {
+	tests := map[string]string{
+		"**bold** text":       "<strong>bold</strong> text",
+		"one\n\ntwo":          "<p>one</p>\n<p>two</p>",
+		"# Heading":           "<h1 id=\"heading\">Heading</h1>",
+		"<b>raw</b> stripped": "<!-- raw HTML omitted -->raw<!-- raw HTML omitted --> stripped",
+	}
+	for in, want := range tests {
+		got, err := markdownify(in, false)
+		if err != nil {
+			t.Errorf("markdownify(%q): %v", in, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("markdownify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSafeHTML(t *testing.T) {
+	data := map[string]string{"html": "<em>hi</em>"}
+	if got := execute(t, `{{.html}}`, data); got != "&lt;em&gt;hi&lt;/em&gt;" {
+		t.Errorf("escaped = %q", got)
+	}
+	if got := execute(t, `{{safeHTML .html}}`, data); got != "<em>hi</em>" {
+		t.Errorf("safeHTML = %q", got)
+	}
+}
+
+func TestJsonify(t *testing.T) {
+	got := execute(t, `<script type="application/ld+json">{{jsonify .}}</script>`, map[string]any{"name": "Sprout", "tags": []string{"a"}})
+	if want := `<script type="application/ld+json">{"name":"Sprout","tags":["a"]}</script>`; got != want {
+		t.Errorf("jsonify = %q, want %q", got, want)
+	}
+
+	if _, err := jsonify(make(chan int)); err == nil {
+		t.Error("jsonify of a channel: expected an error")
+	}
+}
+
+func TestDict(t *testing.T) {
+	got, err := dict("a", 1, "b", "two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"a": 1, "b": "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dict = %v, want %v", got, want)
+	}
+
+	if _, err := dict("a"); err == nil {
+		t.Error("dict with an odd number of arguments: expected an error")
+	}
+	if _, err := dict(1, "a"); err == nil {
+		t.Error("dict with a non-string key: expected an error")
+	}
+
+	if got := execute(t, `{{with dict "Name" "x"}}{{.Name}}{{end}}`, nil); got != "x" {
+		t.Errorf("dict in a template = %q", got)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	if got := slice("a", 1, true); !reflect.DeepEqual(got, []any{"a", 1, true}) {
+		t.Errorf("slice = %v", got)
+	}
+	if got := execute(t, `{{range slice "x" "y"}}{{.}}{{end}}`, nil); got != "xy" {
+		t.Errorf("slice in a template = %q", got)
+	}
+}
@@ -0,0 +1,43 @@
+package template
+
+import (
+	"html/template"
+	"path"
+	"strings"
+
+	"sprout/internal/model"
+)
+
+const markupDir = "_markup/"
+
+// hookName returns the element a render hook template is for, such as
+// "link" for _markup/render-link.html or "codeblock-mermaid" for
+// _markup/render-codeblock-mermaid.html.
+func hookName(file string) (string, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(file, markupDir), ".html")
+	if path.Dir(name) != "." || !strings.HasPrefix(name, "render-") {
+		return "", false
+	}
+	return strings.TrimPrefix(name, "render-"), true
+}
+
+// RenderHook executes the render hook template for a Markdown element. It
+// returns false if the site has no template for it, so the element is
+// rendered as usual.
+func (r *Renderer) RenderHook(name string, ctx *model.HookContext) (template.HTML, bool, error) {
+	t, ok := r.hooks[name]
+	if !ok {
+		return "", false, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.page = ctx.Page
+	defer func() { r.page = nil }()
+
+	var buf strings.Builder
+	if err := t.Execute(&buf, ctx); err != nil {
+		return "", false, err
+	}
+	return template.HTML(buf.String()), true, nil
+}
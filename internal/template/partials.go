@@ -0,0 +1,76 @@
+package template
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+const (
+	partialsDir = "partials/"
+	// maxPartialDepth stops partials that include themselves before they
+	// overflow the stack.
+	maxPartialDepth = 100
+)
+
+// partial renders templates/partials/<name> with the given data, usually the
+// caller's context: {{partial "card.html" .}}. The ".html" extension is
+// optional.
+func (r *Renderer) partial(name string, data ...any) (template.HTML, error) {
+	var context any
+	switch len(data) {
+	case 0:
+	case 1:
+		context = data[0]
+	default:
+		return "", fmt.Errorf("partial %q: expected at most one data argument, got %d", name, len(data))
+	}
+
+	t, ok := r.partials[name]
+	if !ok && !strings.HasSuffix(name, ".html") {
+		name += ".html"
+		t, ok = r.partials[name]
+	}
+	if !ok {
+		return "", r.partialError(name, fmt.Errorf("not found in templates/%s", partialsDir))
+	}
+
+	if r.partialDepth >= maxPartialDepth {
+		return "", r.partialError(name, fmt.Errorf("partials nested more than %d deep", maxPartialDepth))
+	}
+	r.partialDepth++
+	defer func() { r.partialDepth-- }()
+
+	var buf strings.Builder
+	if err := t.Execute(&buf, context); err != nil {
+		return "", r.partialError(name, err)
+	}
+	return template.HTML(buf.String()), nil
+}
+
+// partialCached renders a partial once per build and reuses the output on
+// every page. Extra arguments become part of the cache key, so
+// {{partialCached "nav.html" . .Page.Section}} renders once per section.
+func (r *Renderer) partialCached(name string, data any, keys ...any) (template.HTML, error) {
+	key := name
+	for _, k := range keys {
+		key += "\x00" + fmt.Sprint(k)
+	}
+	if html, ok := r.partialCache[key]; ok {
+		return html, nil
+	}
+
+	html, err := r.partial(name, data)
+	if err != nil {
+		return "", err
+	}
+	r.partialCache[key] = html
+	return html, nil
+}
+
+func (r *Renderer) partialError(name string, err error) error {
+	if r.page != nil {
+		return fmt.Errorf("partial %s%s (called from %s): %w", partialsDir, name, pageName(r.page), err)
+	}
+	return fmt.Errorf("partial %s%s: %w", partialsDir, name, err)
+}
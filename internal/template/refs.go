@@ -0,0 +1,27 @@
+package template
+
+import "fmt"
+
+// SetRefs sets the function that maps a content file to its permalink for
+// the ref template function. from names the page being rendered, for
+// warnings about missing files.
+func (r *Renderer) SetRefs(refs func(from, target string) (string, error)) {
+	r.refs = refs
+}
+
+// ref returns the URL of a content file, given its path in the content
+// directory: {{ref "blog/post.md"}}.
+func (r *Renderer) ref(target string) (string, error) {
+	if r.refs == nil {
+		return "", fmt.Errorf("ref %q: content files are not available", target)
+	}
+	var from string
+	if r.page != nil {
+		from = pageName(r.page)
+	}
+	permalink, err := r.refs(from, target)
+	if err != nil {
+		return "", fmt.Errorf("ref %q: %w", target, err)
+	}
+	return permalink, nil
+}
@@ -5,16 +5,52 @@ import (
 	"html/template"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"text/template/parse"
 
+	"sprout/internal/logx"
 	"sprout/internal/model"
 )
 
+const baseTemplate = "base.html"
+
 type Renderer struct {
-	templates *template.Template
+	shared     *template.Template
+	layouts    map[string]layout
+	partials   map[string]*template.Template
+	shortcodes map[string]*template.Template
+	hooks      map[string]*template.Template
+	hasBase    bool
+	// refs resolves the ref function's argument; see SetRefs.
+	refs func(from, target string) (string, error)
+
+	// mu serializes RenderPage and RenderShortcode, which track the page
+	// being rendered and the partialCached results for error messages and
+	// caching.
+	mu           sync.Mutex
+	page         *model.Page
+	partialDepth int
+	partialCache map[string]template.HTML
+}
+
+// layout is a template that renders whole pages. A layout that defines the
+// "content" block is parsed into its own copy of the shared set and rendered
+// through base.html; any other layout is a page of its own and stays in the
+// shared set, where other templates can include it by name.
+type layout struct {
+	set     *template.Template
+	content bool
+}
+
+type templateFile struct {
+	name string
+	data string
 }
 
-func NewRenderer(templatesDir string) (*Renderer, error) {
+func NewRenderer(resolved *model.ResolvedConfig) (*Renderer, error) {
+	templatesDir := resolved.Paths.Templates
 	if templatesDir == "" {
 		return nil, fmt.Errorf("templates directory cannot be empty")
 	}
@@ -23,8 +59,6 @@ func NewRenderer(templatesDir string) (*Renderer, error) {
 		return nil, fmt.Errorf("templates directory does not exist: %s", templatesDir)
 	}
 
-	tmpl := template.New("base")
-
 	var templateFiles []string
 	err := filepath.Walk(templatesDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -47,6 +81,21 @@ func NewRenderer(templatesDir string) (*Renderer, error) {
 		return nil, fmt.Errorf("no template files found in %s", templatesDir)
 	}
 
+	sort.Strings(templateFiles)
+
+	r := &Renderer{
+		partials:     make(map[string]*template.Template),
+		shortcodes:   make(map[string]*template.Template),
+		hooks:        make(map[string]*template.Template),
+		partialCache: make(map[string]template.HTML),
+	}
+	funcs := funcMap(resolved)
+	funcs["partial"] = r.partial
+	funcs["partialCached"] = r.partialCached
+	funcs["ref"] = r.ref
+
+	var sharedFiles, layoutFiles, partialFiles, shortcodeFiles, hookFiles []templateFile
+	var standalone []string
 	for _, path := range templateFiles {
 		data, err := os.ReadFile(path)
 		if err != nil {
@@ -57,38 +106,235 @@ func NewRenderer(templatesDir string) (*Renderer, error) {
 			return nil, fmt.Errorf("template file is empty: %s", path)
 		}
 
-		if _, err := tmpl.Parse(string(data)); err != nil {
+		relPath, err := filepath.Rel(templatesDir, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relative path: %w", err)
+		}
+		file := templateFile{name: filepath.ToSlash(relPath), data: string(data)}
+
+		if strings.HasPrefix(file.name, partialsDir) {
+			partialFiles = append(partialFiles, file)
+			continue
+		}
+		if strings.HasPrefix(file.name, shortcodesDir) {
+			shortcodeFiles = append(shortcodeFiles, file)
+			continue
+		}
+		if strings.HasPrefix(file.name, markupDir) {
+			hookFiles = append(hookFiles, file)
+			continue
+		}
+
+		probe, err := template.New(file.name).Funcs(funcs).Parse(file.data)
+		if err != nil {
 			return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
 		}
+
+		switch {
+		case file.name == baseTemplate:
+			sharedFiles = append(sharedFiles, file)
+		case probe.Lookup("content") != nil:
+			layoutFiles = append(layoutFiles, file)
+		case hasMarkup(probe, file.name):
+			sharedFiles = append(sharedFiles, file)
+			standalone = append(standalone, file.name)
+		default:
+			sharedFiles = append(sharedFiles, file)
+		}
+	}
+
+	// Base templates and standalone layouts are shared by every layout. Each
+	// layout with a "content" block is parsed into its own clone so that
+	// their blocks don't collide.
+	shared := template.New("").Funcs(funcs)
+	for _, file := range sharedFiles {
+		if _, err := shared.New(file.name).Parse(file.data); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", file.name, err)
+		}
+	}
+
+	layouts := make(map[string]layout, len(layoutFiles)+len(standalone))
+	for _, file := range layoutFiles {
+		set, err := shared.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("failed to clone base templates for %s: %w", file.name, err)
+		}
+		if _, err := set.New(file.name).Parse(file.data); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", file.name, err)
+		}
+		layouts[strings.TrimSuffix(file.name, ".html")] = layout{set: set, content: true}
+	}
+	for _, name := range standalone {
+		layouts[strings.TrimSuffix(name, ".html")] = layout{set: shared}
+	}
+
+	// Partials, shortcodes and render hooks are parsed on their own rather
+	// than into the shared set, so their blocks can't clash with the
+	// layouts' and they can only be used through the partial function or
+	// from content.
+	for _, file := range partialFiles {
+		t, err := template.New(file.name).Funcs(funcs).Parse(file.data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", file.name, err)
+		}
+		r.partials[strings.TrimPrefix(file.name, partialsDir)] = t
+	}
+
+	for _, file := range shortcodeFiles {
+		t, err := template.New(file.name).Funcs(funcs).Parse(file.data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", file.name, err)
+		}
+		r.shortcodes[strings.TrimSuffix(strings.TrimPrefix(file.name, shortcodesDir), ".html")] = t
+	}
+
+	for _, file := range hookFiles {
+		name, ok := hookName(file.name)
+		if !ok {
+			logx.Warnf("ignoring %s: render hook templates are named %srender-<element>.html", file.name, markupDir)
+			continue
+		}
+		t, err := template.New(file.name).Funcs(funcs).Parse(file.data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", file.name, err)
+		}
+		r.hooks[name] = t
+	}
+
+	r.shared = shared
+	r.layouts = layouts
+	r.hasBase = shared.Lookup(baseTemplate) != nil
+	return r, nil
+}
+
+// hasMarkup reports whether a template file has top-level markup of its own,
+// which makes it usable as a standalone layout. Files that only define
+// blocks are not.
+func hasMarkup(t *template.Template, name string) bool {
+	file := t.Lookup(name)
+	if file == nil || file.Tree == nil || file.Tree.Root == nil {
+		return false
+	}
+	for _, node := range file.Tree.Root.Nodes {
+		if text, ok := node.(*parse.TextNode); ok && strings.TrimSpace(string(text.Text)) == "" {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// lookupLayout finds the template for a page. A layout set in front matter
+// is looked up in the page's section first, then in _default/, then at the
+// top level. Without one, the candidates depend on the page kind:
+//
+//	page:     <section>/single, _default/single
+//	section:  <section>/list, _default/list
+//	home:     home, _default/home, _default/list
+//	taxonomy: <taxonomy>/taxonomy, taxonomy, _default/taxonomy, _default/list
+//	term:     <taxonomy>/term, term, _default/term, _default/list
+//
+// page.html, and then base.html on its own, are the last resort for every
+// page.
+func (r *Renderer) lookupLayout(page *model.Page) (layout, string, error) {
+	if page.Layout != "" {
+		for _, name := range sectionCandidates(page.Section, page.Layout) {
+			if l, ok := r.layouts[name]; ok {
+				return l, name + ".html", nil
+			}
+		}
+		logx.Warnf("layout %q not found for %s, using the default template", page.Layout, pageName(page))
+	}
+
+	for _, name := range kindCandidates(page) {
+		if l, ok := r.layouts[name]; ok {
+			return l, name + ".html", nil
+		}
+	}
+
+	if l, ok := r.layouts["page"]; ok {
+		return l, "page.html", nil
 	}
 
-	return &Renderer{templates: tmpl}, nil
+	if r.hasBase {
+		return layout{set: r.shared}, baseTemplate, nil
+	}
+
+	return layout{}, "", fmt.Errorf("no template found for %s: expected one of %s, page.html or base.html in templates directory", pageName(page), strings.Join(withExt(kindCandidates(page)), ", "))
 }
 
-func (r *Renderer) RenderPage(site model.Site, page model.Page) ([]byte, error) {
-	templateName := page.Layout
-	if templateName == "" {
-		templateName = "page"
+func sectionCandidates(section, name string) []string {
+	var candidates []string
+	if section != "" {
+		candidates = append(candidates, section+"/"+name)
 	}
+	return append(candidates, "_default/"+name, name)
+}
 
-	var t *template.Template
-	if r.templates.Lookup("base.html") != nil {
-		t = r.templates.Lookup("base.html")
-	} else {
-		layoutTemplateName := templateName + ".html"
-		if r.templates.Lookup(layoutTemplateName) != nil {
-			t = r.templates.Lookup(layoutTemplateName)
-		} else if r.templates.Lookup("page.html") != nil {
-			t = r.templates.Lookup("page.html")
-		} else {
-			t = r.templates
+func kindCandidates(page *model.Page) []string {
+	var candidates []string
+	switch page.Kind {
+	case model.KindHome:
+		candidates = []string{"home", "_default/home"}
+	case model.KindSection:
+		if page.Section != "" {
+			candidates = append(candidates, page.Section+"/list")
+		}
+	case model.KindTaxonomy, model.KindTerm:
+		if page.Section != "" {
+			candidates = append(candidates, page.Section+"/"+page.Kind)
+		}
+		candidates = append(candidates, page.Kind, "_default/"+page.Kind)
+	default:
+		if page.Section != "" {
+			candidates = append(candidates, page.Section+"/single")
 		}
+		return append(candidates, "_default/single")
+	}
+	return append(candidates, "_default/list")
+}
+
+func withExt(names []string) []string {
+	result := make([]string, len(names))
+	for i, name := range names {
+		result[i] = name + ".html"
+	}
+	return result
+}
+
+func pageName(page *model.Page) string {
+	if page.SourcePath != "" {
+		return page.SourcePath
+	}
+	return page.RelPermalink
+}
+
+// RenderPage executes the page's layout. paginator is nil unless the page is
+// a paginated list.
+func (r *Renderer) RenderPage(site *model.Site, page *model.Page, paginator *model.Paginator) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.page = page
+	defer func() { r.page = nil }()
+
+	l, name, err := r.lookupLayout(page)
+	if err != nil {
+		return nil, err
+	}
+
+	logx.Infof("Template: %s -> %s", pageName(page), name)
+
+	// Layouts that only fill in the "content" block are rendered through
+	// base.html; standalone layouts are complete pages.
+	if l.content && r.hasBase {
+		name = baseTemplate
 	}
 
 	var buf strings.Builder
-	if err := t.Execute(&buf, map[string]interface{}{
-		"Site": site,
-		"Page": page,
+	if err := l.set.ExecuteTemplate(&buf, name, map[string]interface{}{
+		"Site":      site,
+		"Page":      page,
+		"Paginator": paginator,
 	}); err != nil {
 		return nil, fmt.Errorf("failed to render template: %w", err)
 	}
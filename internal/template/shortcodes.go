@@ -0,0 +1,34 @@
+package template
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"sprout/internal/model"
+)
+
+const shortcodesDir = "shortcodes/"
+
+// RenderShortcode executes templates/shortcodes/<name>.html for a shortcode
+// call in a page's content. The template's data is the shortcode itself, so
+// it can use .Get, .Params, .Inner and .Page.
+func (r *Renderer) RenderShortcode(sc *model.Shortcode) (template.HTML, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.page = sc.Page
+	defer func() { r.page = nil }()
+
+	t, ok := r.shortcodes[sc.Name]
+	if !ok {
+		return "", fmt.Errorf("template %s%s.html not found", shortcodesDir, sc.Name)
+	}
+
+	var buf strings.Builder
+	if err := t.Execute(&buf, sc); err != nil {
+		return "", err
+	}
+	// Trailing newlines in the template file would otherwise end up in the
+	// middle of the text when a shortcode is used inline.
+	return template.HTML(strings.TrimSpace(buf.String())), nil
+}